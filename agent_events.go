@@ -0,0 +1,238 @@
+package ragflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// AgentEvent is an event of an agent run, as streamed by RunAgentStreamWith.
+// It is one of *AgentMessageEvent, *AgentMessageEndEvent,
+// *AgentNodeStartedEvent, *AgentNodeFinishedEvent,
+// *AgentWorkflowFinishedEvent and *AgentUnknownEvent.
+//
+//	for event := range events {
+//		switch e := event.(type) {
+//		case *ragflow.AgentMessageEvent:
+//			fmt.Print(e.Content)
+//		case *ragflow.AgentNodeFinishedEvent:
+//			log.Printf("%s finished in %s", e.ComponentID, e.Elapsed)
+//		}
+//	}
+type AgentEvent interface {
+	// Type is the name of the event on the wire, such as "node_started".
+	Type() string
+	agentEvent()
+}
+
+// AgentEventHeader holds the fields common to all events.
+type AgentEventHeader struct {
+	Event     string
+	MessageID string
+	TaskID    string
+	SessionID string
+	CreatedAt time.Time
+}
+
+func (h *AgentEventHeader) Type() string { return h.Event }
+func (h *AgentEventHeader) agentEvent()  {}
+
+// AgentMessageEvent is a piece of the answer.
+type AgentMessageEvent struct {
+	AgentEventHeader
+	Content string
+	// Chunk is set when the server streams OpenAI style chunks instead of
+	// typed events.
+	Chunk *ChatCompletionResponse
+}
+
+// AgentMessageEndEvent ends the answer.
+type AgentMessageEndEvent struct {
+	AgentEventHeader
+	// Reference is left empty if the server's format is not recognized.
+	Reference ChatCompletionReference
+}
+
+type AgentNodeStartedEvent struct {
+	AgentEventHeader
+	ComponentID   string
+	ComponentName string
+	ComponentType string
+	Inputs        map[string]interface{}
+	Thoughts      string
+}
+
+type AgentNodeFinishedEvent struct {
+	AgentEventHeader
+	ComponentID   string
+	ComponentName string
+	ComponentType string
+	Inputs        map[string]interface{}
+	Outputs       map[string]interface{}
+	// Error is the component's error, if it failed.
+	Error   string
+	Elapsed time.Duration
+}
+
+// AgentWorkflowFinishedEvent ends the run. Outputs holds the final answer
+// under "content".
+type AgentWorkflowFinishedEvent struct {
+	AgentEventHeader
+	Inputs  map[string]interface{}
+	Outputs map[string]interface{}
+	Error   string
+	Elapsed time.Duration
+}
+
+// AgentUnknownEvent is an event this package does not know about.
+type AgentUnknownEvent struct {
+	AgentEventHeader
+	Data json.RawMessage
+}
+
+type agentEventData struct {
+	ComponentID   string                 `json:"component_id"`
+	ComponentName string                 `json:"component_name"`
+	ComponentType string                 `json:"component_type"`
+	Content       string                 `json:"content"`
+	Inputs        map[string]interface{} `json:"inputs"`
+	Outputs       map[string]interface{} `json:"outputs"`
+	Thoughts      string                 `json:"thoughts"`
+	Error         json.RawMessage        `json:"error"`
+	ElapsedTime   float64                `json:"elapsed_time"`
+	Reference     json.RawMessage        `json:"reference"`
+}
+
+// decodeAgentEvent decodes the data of one server-sent event. Data without
+// an event name is an OpenAI style chunk.
+func decodeAgentEvent(data []byte) (AgentEvent, error) {
+	var envelope struct {
+		Event     string          `json:"event"`
+		MessageID string          `json:"message_id"`
+		TaskID    string          `json:"task_id"`
+		SessionID string          `json:"session_id"`
+		CreatedAt float64         `json:"created_at"`
+		Data      json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("error unmarshaling stream data: %w", err)
+	}
+
+	if envelope.Event == "" {
+		var chunk ChatCompletionResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return nil, fmt.Errorf("error unmarshaling stream data: %w", err)
+		}
+		event := &AgentMessageEvent{
+			AgentEventHeader: AgentEventHeader{Event: "message", MessageID: chunk.ID, CreatedAt: unixSeconds(float64(chunk.Created))},
+			Chunk:            &chunk,
+		}
+		if len(chunk.Choices) > 0 {
+			event.Content = chunk.Choices[0].Delta.Content
+		}
+		return event, nil
+	}
+
+	header := AgentEventHeader{
+		Event:     envelope.Event,
+		MessageID: envelope.MessageID,
+		TaskID:    envelope.TaskID,
+		SessionID: envelope.SessionID,
+		CreatedAt: unixSeconds(envelope.CreatedAt),
+	}
+
+	var d agentEventData
+	switch envelope.Event {
+	case "message", "message_end", "node_started", "node_finished", "workflow_finished":
+		if len(envelope.Data) > 0 && string(envelope.Data) != "null" {
+			if err := json.Unmarshal(envelope.Data, &d); err != nil {
+				return nil, fmt.Errorf("error unmarshaling %s event: %w", envelope.Event, err)
+			}
+		}
+	}
+
+	switch envelope.Event {
+	case "message":
+		return &AgentMessageEvent{AgentEventHeader: header, Content: d.Content}, nil
+	case "message_end":
+		event := &AgentMessageEndEvent{AgentEventHeader: header}
+		if len(d.Reference) > 0 {
+			_ = json.Unmarshal(d.Reference, &event.Reference)
+		}
+		return event, nil
+	case "node_started":
+		return &AgentNodeStartedEvent{
+			AgentEventHeader: header,
+			ComponentID:      d.ComponentID,
+			ComponentName:    d.ComponentName,
+			ComponentType:    d.ComponentType,
+			Inputs:           d.Inputs,
+			Thoughts:         d.Thoughts,
+		}, nil
+	case "node_finished":
+		return &AgentNodeFinishedEvent{
+			AgentEventHeader: header,
+			ComponentID:      d.ComponentID,
+			ComponentName:    d.ComponentName,
+			ComponentType:    d.ComponentType,
+			Inputs:           d.Inputs,
+			Outputs:          d.Outputs,
+			Error:            eventError(d.Error),
+			Elapsed:          seconds(d.ElapsedTime),
+		}, nil
+	case "workflow_finished":
+		return &AgentWorkflowFinishedEvent{
+			AgentEventHeader: header,
+			Inputs:           d.Inputs,
+			Outputs:          d.Outputs,
+			Error:            eventError(d.Error),
+			Elapsed:          seconds(d.ElapsedTime),
+		}, nil
+	}
+	return &AgentUnknownEvent{AgentEventHeader: header, Data: envelope.Data}, nil
+}
+
+// completionChunk converts a message event to the chunk RunAgentStream
+// delivers.
+func (e *AgentMessageEvent) completionChunk(agentID string) ChatCompletionResponse {
+	if e.Chunk != nil {
+		return *e.Chunk
+	}
+	var created int64
+	if !e.CreatedAt.IsZero() {
+		created = e.CreatedAt.Unix()
+	}
+	return ChatCompletionResponse{
+		ID:      e.MessageID,
+		Object:  "chat.completion.chunk",
+		Created: created,
+		Model:   agentID,
+		Choices: []ChatCompletionChoice{{Delta: ChatMessage{Role: "assistant", Content: e.Content}}},
+	}
+}
+
+// eventError returns the error of a node as text. RAGFlow sends null when
+// there is none.
+func eventError(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
+
+func unixSeconds(s float64) time.Time {
+	if s == 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(s)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -4,10 +4,10 @@ import (
 	"bufio"
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strconv"
 )
 
@@ -112,19 +112,101 @@ func (c *Client) ListAgents(ctx context.Context, opts *ListAgentsOptions) (*List
 	return &resp, nil
 }
 
+// RunAgent asks an agent a question. Use RunAgentWith to pass the inputs of
+// the agent's Begin component or files.
 func (c *Client) RunAgent(ctx context.Context, agentID string, message string, sessionID string) (*ChatCompletionResponse, error) {
-	req := ChatCompletionRequest{
-		Messages: []ChatMessage{
-			{
-				Role:    "user",
-				Content: message,
+	return c.RunAgentWith(ctx, agentID, RunAgentRequest{Question: message, SessionID: sessionID})
+}
+
+// RunAgentStream asks an agent a question and streams the answer as chat
+// completion chunks. Use RunAgentStreamWith to follow the agent's components
+// as they run.
+func (c *Client) RunAgentStream(ctx context.Context, agentID string, message string, sessionID string) (<-chan ChatCompletionResponse, <-chan error) {
+	events, eventErrChan := c.RunAgentStreamWith(ctx, agentID, RunAgentRequest{Question: message, SessionID: sessionID})
+
+	respChan := make(chan ChatCompletionResponse)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(respChan)
+		defer close(errChan)
+
+		for event := range events {
+			var chunk ChatCompletionResponse
+			switch e := event.(type) {
+			case *AgentMessageEvent:
+				chunk = e.completionChunk(agentID)
+			case *AgentMessageEndEvent:
+				if len(e.Reference.Chunks) == 0 {
+					continue
+				}
+				chunk = ChatCompletionResponse{ID: e.MessageID, Object: "chat.completion.chunk", Model: agentID, Reference: e.Reference}
+			default:
+				continue
+			}
+
+			select {
+			case respChan <- chunk:
+			case <-ctx.Done():
+				errChan <- ctx.Err()
+				return
+			}
+		}
+
+		if err := <-eventErrChan; err != nil {
+			errChan <- err
+		}
+	}()
+
+	return respChan, errChan
+}
+
+type agentCompletionRequest struct {
+	ChatCompletionRequest
+	UserID string                `json:"user_id,omitempty"`
+	Inputs map[string]AgentInput `json:"inputs,omitempty"`
+	Files  []AgentFile           `json:"files,omitempty"`
+}
+
+// agentCompletionBody uploads the attachments of req and returns the body of
+// the completions request.
+func (c *Client) agentCompletionBody(ctx context.Context, agentID string, req RunAgentRequest, stream bool) (*agentCompletionRequest, error) {
+	files := append([]AgentFile(nil), req.Files...)
+	for _, f := range req.Attachments {
+		file, err := c.UploadAgentFile(ctx, agentID, f)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, *file)
+	}
+
+	return &agentCompletionRequest{
+		ChatCompletionRequest: ChatCompletionRequest{
+			Messages: []ChatMessage{
+				{
+					Role:    "user",
+					Content: req.Question,
+				},
 			},
+			ConversationID: req.SessionID,
+			Stream:         stream,
 		},
-		ConversationID: sessionID,
+		UserID: req.UserID,
+		Inputs: req.Inputs,
+		Files:  files,
+	}, nil
+}
+
+// RunAgentWith asks an agent a question and waits for the whole answer.
+// Attachments are uploaded before the question is sent.
+func (c *Client) RunAgentWith(ctx context.Context, agentID string, req RunAgentRequest) (*ChatCompletionResponse, error) {
+	body, err := c.agentCompletionBody(ctx, agentID, req, false)
+	if err != nil {
+		return nil, err
 	}
 
 	endpoint := fmt.Sprintf("/api/v1/agents/%s/completions", agentID)
-	httpReq, err := c.newRequest(ctx, http.MethodPost, endpoint, req)
+	httpReq, err := c.newRequest(ctx, http.MethodPost, endpoint, body)
 	if err != nil {
 		return nil, err
 	}
@@ -137,27 +219,25 @@ func (c *Client) RunAgent(ctx context.Context, agentID string, message string, s
 	return &resp, nil
 }
 
-func (c *Client) RunAgentStream(ctx context.Context, agentID string, message string, sessionID string) (<-chan ChatCompletionResponse, <-chan error) {
-	req := ChatCompletionRequest{
-		Messages: []ChatMessage{
-			{
-				Role:    "user",
-				Content: message,
-			},
-		},
-		ConversationID: sessionID,
-		Stream:         true,
-	}
-
-	respChan := make(chan ChatCompletionResponse)
+// RunAgentStreamWith is RunAgentWith with the run streamed as events: the
+// pieces of the answer and the start and end of each component. Servers that
+// stream chat completion chunks instead deliver only message events.
+func (c *Client) RunAgentStreamWith(ctx context.Context, agentID string, req RunAgentRequest) (<-chan AgentEvent, <-chan error) {
+	respChan := make(chan AgentEvent)
 	errChan := make(chan error, 1)
 
 	go func() {
 		defer close(respChan)
 		defer close(errChan)
 
+		body, err := c.agentCompletionBody(ctx, agentID, req, true)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
 		endpoint := fmt.Sprintf("/api/v1/agents/%s/completions", agentID)
-		httpReq, err := c.newRequest(ctx, http.MethodPost, endpoint, req)
+		httpReq, err := c.newRequest(ctx, http.MethodPost, endpoint, body)
 		if err != nil {
 			errChan <- err
 			return
@@ -166,19 +246,13 @@ func (c *Client) RunAgentStream(ctx context.Context, agentID string, message str
 		httpReq.Header.Set("Accept", "text/event-stream")
 		httpReq.Header.Set("Cache-Control", "no-cache")
 
-		resp, err := c.HTTPClient.Do(httpReq)
+		resp, err := c.send(httpReq)
 		if err != nil {
-			errChan <- fmt.Errorf("error making streaming request: %w", err)
+			errChan <- err
 			return
 		}
 		defer resp.Body.Close()
 
-		if resp.StatusCode >= 400 {
-			bodyBytes, _ := io.ReadAll(resp.Body)
-			errChan <- c.handleErrorResponse(resp.StatusCode, bodyBytes)
-			return
-		}
-
 		reader := bufio.NewReader(resp.Body)
 		for {
 			line, err := reader.ReadBytes('\n')
@@ -195,23 +269,29 @@ func (c *Client) RunAgentStream(ctx context.Context, agentID string, message str
 				continue
 			}
 
-			if !bytes.HasPrefix(line, []byte("data: ")) {
+			// RAGFlow writes agent events without a space after "data:".
+			if !bytes.HasPrefix(line, []byte("data:")) {
 				continue
 			}
 
-			data := bytes.TrimPrefix(line, []byte("data: "))
+			data := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
 			if bytes.Equal(data, []byte("[DONE]")) {
 				break
 			}
 
-			var streamResp ChatCompletionResponse
-			if err := json.Unmarshal(data, &streamResp); err != nil {
-				errChan <- fmt.Errorf("error unmarshaling stream data: %w", err)
+			if err := streamError(httpReq, data); err != nil {
+				errChan <- err
+				return
+			}
+
+			event, err := decodeAgentEvent(data)
+			if err != nil {
+				errChan <- err
 				return
 			}
 
 			select {
-			case respChan <- streamResp:
+			case respChan <- event:
 			case <-ctx.Done():
 				errChan <- ctx.Err()
 				return
@@ -220,4 +300,148 @@ func (c *Client) RunAgentStream(ctx context.Context, agentID string, message str
 	}()
 
 	return respChan, errChan
-}
\ No newline at end of file
+}
+
+type CreateAgentSessionRequest struct {
+	// UserID is an identifier of the user of your application, stored with
+	// the session.
+	UserID string
+	// Inputs are the values of the parameters declared by the agent's Begin
+	// component, by key. The sessions endpoint takes bare values, so only
+	// their Value is sent.
+	Inputs map[string]AgentInput
+}
+
+// CreateAgentSession starts a session with an agent. The session's messages
+// hold the agent's prologue. req may be nil if the agent takes no inputs.
+func (c *Client) CreateAgentSession(ctx context.Context, agentID string, req *CreateAgentSessionRequest) (*AgentSession, error) {
+	params := make(map[string]string)
+	body := map[string]interface{}{}
+	if req != nil {
+		if req.UserID != "" {
+			params["user_id"] = req.UserID
+		}
+		for key, input := range req.Inputs {
+			body[key] = input.Value
+		}
+	}
+
+	url := c.buildURL(fmt.Sprintf("/api/v1/agents/%s/sessions", agentID), params)
+	httpReq, err := c.newRequest(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp Response[AgentSession]
+	if err := c.do(httpReq, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp.Data, nil
+}
+
+type ListAgentSessionsOptions struct {
+	Page     int
+	PageSize int
+	OrderBy  string
+	Desc     bool
+	ID       string
+	UserID   string
+	// IncludeDSL returns each session's copy of the agent DSL.
+	IncludeDSL bool
+}
+
+// ListAgentSessions lists the sessions of an agent with their messages.
+func (c *Client) ListAgentSessions(ctx context.Context, agentID string, opts *ListAgentSessionsOptions) (*ListResponse[AgentSession], error) {
+	params := map[string]string{"dsl": "false"}
+
+	if opts != nil {
+		if opts.Page > 0 {
+			params["page"] = strconv.Itoa(opts.Page)
+		}
+		if opts.PageSize > 0 {
+			params["page_size"] = strconv.Itoa(opts.PageSize)
+		}
+		if opts.OrderBy != "" {
+			params["orderby"] = opts.OrderBy
+		}
+		if opts.Desc {
+			params["desc"] = "true"
+		}
+		if opts.ID != "" {
+			params["id"] = opts.ID
+		}
+		if opts.UserID != "" {
+			params["user_id"] = opts.UserID
+		}
+		if opts.IncludeDSL {
+			params["dsl"] = "true"
+		}
+	}
+
+	endpoint := fmt.Sprintf("/api/v1/agents/%s/sessions", agentID)
+	url := c.buildURL(endpoint, params)
+	httpReq, err := c.newRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp ListResponse[AgentSession]
+	if err := c.do(httpReq, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+func (c *Client) DeleteAgentSessions(ctx context.Context, agentID string, sessionIDs []string) error {
+	endpoint := fmt.Sprintf("/api/v1/agents/%s/sessions", agentID)
+	httpReq, err := c.newRequest(ctx, http.MethodDelete, endpoint, struct {
+		IDs []string `json:"ids"`
+	}{
+		IDs: sessionIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.do(httpReq, nil)
+}
+
+// UploadAgentFile uploads a file for use in the agent's runs, as a FileInput
+// or in RunAgentRequest.Files.
+func (c *Client) UploadAgentFile(ctx context.Context, agentID string, f UploadFile) (*AgentFile, error) {
+	part := uploadPart{
+		filename:    uploadFileName(f),
+		contentType: f.ContentType,
+		reader:      f.Reader,
+		size:        -1,
+	}
+	switch {
+	case f.Path != "":
+		file, err := os.Open(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("error opening file: %w", err)
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("error reading file info: %w", err)
+		}
+		part.reader, part.size = file, info.Size()
+	case f.Reader != nil:
+		if f.Size > 0 {
+			part.size = f.Size
+		}
+	default:
+		return nil, fmt.Errorf("upload file has neither Path nor Reader")
+	}
+
+	endpoint := fmt.Sprintf("/api/v1/agents/%s/upload", agentID)
+	var result Response[AgentFile]
+	if err := c.postMultipart(ctx, endpoint, []uploadPart{part}, nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &result.Data, nil
+}
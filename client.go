@@ -2,19 +2,16 @@ package ragflow
 
 import (
 	"bytes"
-	"log"
 	"context"
-	"crypto/rand"
 	"crypto/rsa"
-	"crypto/x509"
-	"encoding/base64"
 	"encoding/json"
-	"encoding/pem"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -28,9 +25,20 @@ type Client struct {
 	APIKey     string
 	Username   string
 	Password   string
+	// Deprecated: use Session and SetSession. SessionAuth and SessionCookie
+	// mirror the stored login session; values set here are used when the
+	// session store holds none.
 	SessionCookie string
-	SessionAuth string
+	SessionAuth   string
 	HTTPClient *http.Client
+	RetryPolicy *RetryPolicy
+	Logger     *slog.Logger
+
+	sessions           SessionStore
+	loginMu            sync.Mutex
+	loginKeySource     PublicKeySource
+	loginKey           *rsa.PublicKey
+	plainPasswordLogin bool
 }
 
 type ClientOption func(*Client)
@@ -47,16 +55,30 @@ func WithHTTPClient(httpClient *http.Client) ClientOption {
 	}
 }
 
+// Deprecated: use WithCredentials. WithUserPass used to log in while the
+// client was created and panic if that failed. It now logs in on the first
+// userland request, which returns any login error.
 func WithUserPass(username string, password string) ClientOption {
+	return WithCredentials(username, password)
+}
+
+// WithRetryPolicy retries transient failures according to policy. A nil
+// policy selects DefaultRetryPolicy.
+func WithRetryPolicy(policy *RetryPolicy) ClientOption {
 	return func(c *Client) {
-		c.Username = username
-		c.Password = password
-		auth, cookie, err := c.Login(context.Background())
-		if err != nil {
-			panic(err)
+		if policy == nil {
+			policy = DefaultRetryPolicy()
 		}
-		c.SessionCookie = cookie
-		c.SessionAuth = auth
+		c.RetryPolicy = policy
+	}
+}
+
+// WithLogger logs requests and responses to logger. Requests and response
+// bodies are logged at debug level with credentials redacted; failures are
+// logged at warn level. The client logs nothing by default.
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(c *Client) {
+		c.Logger = logger
 	}
 }
 
@@ -76,6 +98,7 @@ func NewClient(apiKey string, opts ...ClientOption) *Client {
 		HTTPClient: &http.Client{
 			Timeout: DefaultTimeout,
 		},
+		sessions: &memorySessionStore{},
 	}
 
 	for _, opt := range opts {
@@ -85,48 +108,25 @@ func NewClient(apiKey string, opts ...ClientOption) *Client {
 	return c
 }
 
-func rsaPsw(password string) (string, error) {
-    // The same public key from the JavaScript code
-    pubKeyPEM := `-----BEGIN PUBLIC KEY-----
-MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEArq9XTUSeYr2+N1h3Afl/z8Dse/2yD0ZGrKwx+EEEcdsBLca9Ynmx3nIB5obmLlSfmskLpBo0UACBmB5rEjBp2Q2f3AG3Hjd4B+gNCG6BDaawuDlgANIhGnaTLrIqWrrcm4EMzJOnAOI1fgzJRsOOUEfaS318Eq9OVO3apEyCCt0lOQK6PuksduOjVxtltDav+guVAA068NrPYmRNabVKRNLJpL8w4D44sfth5RvZ3q9t+6RTArpEtc5sh5ChzvqPOzKGMXW83C95TxmXqpbK6olN4RevSfVjEAgCydH6HN6OhtOQEcnrU97r9H0iZOWwbw3pVrZiUkuRD1R56Wzs2wIDAQAB
------END PUBLIC KEY-----`
-
-    // Parse PEM block
-    block, _ := pem.Decode([]byte(pubKeyPEM))
-    if block == nil {
-        return "", fmt.Errorf("failed to parse PEM block")
-    }
-
-    // Parse public key
-    pub, err := x509.ParsePKIXPublicKey(block.Bytes)
-    if err != nil {
-        return "", fmt.Errorf("failed to parse public key: %v", err)
-    }
-
-    // Type assert to RSA public key
-    rsaPub, ok := pub.(*rsa.PublicKey)
-    if !ok {
-        return "", fmt.Errorf("not an RSA public key")
-    }
-
-    // Base64 encode the password (matching Base64.encode(password) in JS)
-    encodedPassword := base64.StdEncoding.EncodeToString([]byte(password))
-
-    // RSA encrypt using PKCS1v15 (same as JSEncrypt default)
-    encrypted, err := rsa.EncryptPKCS1v15(rand.Reader, rsaPub, []byte(encodedPassword))
-    if err != nil {
-        return "", fmt.Errorf("encryption failed: %v", err)
-    }
-
-    // Return base64 encoded result (matching JSEncrypt output)
-    return base64.StdEncoding.EncodeToString(encrypted), nil
+// Login logs in with the client's credentials, stores the new session and
+// returns its Authorization token and session cookie. Userland requests log
+// in automatically, so calling Login is only needed to log in eagerly.
+func (c *Client) Login(ctx context.Context) (string, string, error) {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	session, err := c.login(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return session.Auth, session.Cookie, nil
 }
 
-func (c *Client) Login(ctx context.Context) (string, string, error) {
+func (c *Client) postLogin(ctx context.Context) (string, string, error) {
 	url := c.BaseURL + "/v1/user/login"
-	rsaPassword, err := rsaPsw(c.Password)
+	password, err := c.loginPassword(ctx)
 	if err != nil {
-		panic(err)
+		return "", "", fmt.Errorf("error encrypting password: %w", err)
 	}
 
 	body := struct{
@@ -134,7 +134,7 @@ func (c *Client) Login(ctx context.Context) (string, string, error) {
 		Password string `json:"password"`
 	}{
 		Username: c.Username,
-		Password: rsaPassword,
+		Password: password,
 	}
 
 	var buf io.Reader
@@ -151,7 +151,7 @@ func (c *Client) Login(ctx context.Context) (string, string, error) {
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.roundTrip(req)
 	if err != nil {
 		return "", "", fmt.Errorf("error making request: %w", err)
 	}
@@ -161,25 +161,26 @@ func (c *Client) Login(ctx context.Context) (string, string, error) {
 	if err != nil {
 		return "", "", fmt.Errorf("error reading response body: %w", err)
 	}
+	c.logResponseBody(req, bodyBytes)
 
 	if resp.StatusCode >= 400 {
-		return "", "", c.handleErrorResponse(resp.StatusCode, bodyBytes)
+		return "", "", c.responseError(req, resp, bodyBytes)
 	}
 	// Check for API-level errors in the response
-	if err := c.checkAPIResponse(bodyBytes); err != nil {
+	if err := c.checkAPIResponse(req, resp, bodyBytes); err != nil {
 		return "", "", err
 	}
 
 	auth := resp.Header.Get("Authorization")
-	cookie := resp.Header.Get("Set-Cookie")
 	if auth == "" {
-		return "", "", fmt.Errorf("Fail to login")
+		return "", "", fmt.Errorf("Fail to login: %w", ErrUnauthorized)
 	}
-	if cookie == "" {
-		return "", "", fmt.Errorf("Fail to login")
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "session" && cookie.Value != "" {
+			return auth, cookie.Value, nil
+		}
 	}
-
-	return auth, strings.Split(strings.Split(cookie, "session=")[1], ";")[0], nil
+	return "", "", fmt.Errorf("Fail to login: %w", ErrUnauthorized)
 }
 
 func (c *Client) newRequest(ctx context.Context, method, endpoint string, body interface{}) (*http.Request, error) {
@@ -208,7 +209,13 @@ func (c *Client) newRequest(ctx context.Context, method, endpoint string, body i
 }
 
 func (c *Client) do(req *http.Request, v interface{}) error {
-	resp, err := c.HTTPClient.Do(req)
+	return c.withRetry(req, func(req *http.Request) error {
+		return c.doOnce(req, v)
+	})
+}
+
+func (c *Client) doOnce(req *http.Request, v interface{}) error {
+	resp, err := c.roundTrip(req)
 	if err != nil {
 		return fmt.Errorf("error making request: %w", err)
 	}
@@ -219,11 +226,10 @@ func (c *Client) do(req *http.Request, v interface{}) error {
 		return fmt.Errorf("error reading response body: %w", err)
 	}
 
-	log.Println("RAGFLOW REQ:", req)
-	log.Println("RAGFLOW RESP:", string(bodyBytes))
+	c.logResponseBody(req, bodyBytes)
 
 	if resp.StatusCode >= 400 {
-		return c.handleErrorResponse(resp.StatusCode, bodyBytes)
+		return c.responseError(req, resp, bodyBytes)
 	}
 
 	if v != nil {
@@ -232,7 +238,7 @@ func (c *Client) do(req *http.Request, v interface{}) error {
 		}
 
 		// Check for API-level errors in the response
-		if err := c.checkAPIResponse(bodyBytes); err != nil {
+		if err := c.checkAPIResponse(req, resp, bodyBytes); err != nil {
 			return err
 		}
 	}
@@ -240,24 +246,42 @@ func (c *Client) do(req *http.Request, v interface{}) error {
 	return nil
 }
 
-func (c *Client) handleErrorResponse(statusCode int, body []byte) error {
-	var errResp ErrorResponse
-	if err := json.Unmarshal(body, &errResp); err != nil {
-		return &APIError{
-			Code:       statusCode,
-			Message:    fmt.Sprintf("HTTP %d: %s", statusCode, string(body)),
-			StatusCode: statusCode,
+// send performs req under the client's retry policy and returns the response
+// with its body unread. Responses with an error status are turned into errors.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	err := c.withRetry(req, func(req *http.Request) error {
+		r, err := c.roundTrip(req)
+		if err != nil {
+			return fmt.Errorf("error making request: %w", err)
+		}
+
+		if r.StatusCode >= 400 {
+			defer r.Body.Close()
+			bodyBytes, _ := io.ReadAll(r.Body)
+			return c.responseError(req, r, bodyBytes)
 		}
+
+		resp = r
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return &APIError{
-		Code:       errResp.Code,
-		Message:    errResp.Message,
-		StatusCode: statusCode,
+	return resp, nil
+}
+
+func (c *Client) responseError(req *http.Request, resp *http.Response, body []byte) error {
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil || (errResp.Code == 0 && errResp.Message == "") {
+		return newAPIError(req, resp, resp.StatusCode, fmt.Sprintf("HTTP %d: %s", resp.StatusCode, string(body)))
 	}
+
+	return newAPIError(req, resp, errResp.Code, errResp.Message)
 }
 
-func (c *Client) checkAPIResponse(body []byte) error {
+func (c *Client) checkAPIResponse(req *http.Request, resp *http.Response, body []byte) error {
 	var baseResp struct {
 		Code    int    `json:"code"`
 		Message string `json:"message"`
@@ -269,11 +293,8 @@ func (c *Client) checkAPIResponse(body []byte) error {
 
 	// Check if the API returned an error code
 	if baseResp.Code != 0 && baseResp.Code != 200 {
-		return &APIError{
-			Code:       baseResp.Code,
-			Message:    baseResp.Message,
-			StatusCode: 200, // HTTP was OK but API returned error
-		}
+		// HTTP was OK but API returned error
+		return newAPIError(req, resp, baseResp.Code, baseResp.Message)
 	}
 
 	return nil
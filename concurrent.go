@@ -0,0 +1,43 @@
+package ragflow
+
+import (
+	"context"
+	"sync"
+)
+
+// forEachConcurrent calls fn for every index in [0, n) using at most workers
+// goroutines. Indexes not yet started when ctx is done are passed to skip.
+func forEachConcurrent(ctx context.Context, n, workers int, fn func(i int), skip func(i int, err error)) {
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		if ctx.Err() != nil {
+			skip(i, ctx.Err())
+			continue
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			skip(i, ctx.Err())
+		}
+	}
+	close(indexes)
+	wg.Wait()
+}
@@ -0,0 +1,325 @@
+package ragflow
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+type ConversationTarget string
+
+const (
+	ConversationAssistant ConversationTarget = "assistant"
+	ConversationAgent     ConversationTarget = "agent"
+)
+
+// ConversationTurn is a question and the answer it received.
+type ConversationTurn struct {
+	Question  string        `json:"question"`
+	Answer    string        `json:"answer"`
+	Reference ChatReference `json:"reference"`
+}
+
+// ConversationState is the serializable state of a Conversation. Save it,
+// for example with json.Marshal, and continue with ResumeConversation.
+type ConversationState struct {
+	Target      ConversationTarget `json:"target"`
+	TargetID    string             `json:"target_id"`
+	SessionID   string             `json:"session_id,omitempty"`
+	SessionName string             `json:"session_name,omitempty"`
+	// Inputs are passed to CreateAgentSession for agent conversations.
+	Inputs map[string]AgentInput `json:"inputs,omitempty"`
+	Turns  []ConversationTurn    `json:"turns,omitempty"`
+}
+
+type ConversationOptions struct {
+	// SessionName names the session created for an assistant conversation.
+	SessionName string
+	// SessionID continues an existing session instead of creating one.
+	SessionID string
+	// Inputs are the values of the Begin parameters of an agent.
+	Inputs map[string]AgentInput
+}
+
+// Conversation keeps the history of a chat with an assistant or agent. The
+// session is created on the first question. Questions are answered one at a
+// time; a question asked while another is pending waits for it.
+type Conversation struct {
+	client *Client
+
+	mu    sync.Mutex
+	state ConversationState
+}
+
+func (c *Client) NewAssistantConversation(assistantID string, opts *ConversationOptions) *Conversation {
+	return c.newConversation(ConversationAssistant, assistantID, opts)
+}
+
+func (c *Client) NewAgentConversation(agentID string, opts *ConversationOptions) *Conversation {
+	return c.newConversation(ConversationAgent, agentID, opts)
+}
+
+func (c *Client) newConversation(target ConversationTarget, targetID string, opts *ConversationOptions) *Conversation {
+	state := ConversationState{Target: target, TargetID: targetID}
+	if opts != nil {
+		state.SessionID = opts.SessionID
+		state.SessionName = opts.SessionName
+		state.Inputs = opts.Inputs
+	}
+	return c.ResumeConversation(state)
+}
+
+// ResumeConversation continues a conversation from a saved state.
+func (c *Client) ResumeConversation(state ConversationState) *Conversation {
+	state.Turns = append([]ConversationTurn(nil), state.Turns...)
+	return &Conversation{client: c, state: state}
+}
+
+func (cv *Conversation) SessionID() string {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	return cv.state.SessionID
+}
+
+func (cv *Conversation) Turns() []ConversationTurn {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	return append([]ConversationTurn(nil), cv.state.Turns...)
+}
+
+// Messages returns the history as alternating user and assistant messages.
+func (cv *Conversation) Messages() []ChatMessage {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	messages := make([]ChatMessage, 0, 2*len(cv.state.Turns))
+	for _, turn := range cv.state.Turns {
+		messages = append(messages,
+			ChatMessage{Role: "user", Content: turn.Question},
+			ChatMessage{Role: "assistant", Content: turn.Answer},
+		)
+	}
+	return messages
+}
+
+// References returns the chunks cited across all answers, each once, in the
+// order they were first cited.
+func (cv *Conversation) References() []RetrievalChunk {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	var chunks []RetrievalChunk
+	seen := make(map[string]bool)
+	for _, turn := range cv.state.Turns {
+		for _, chunk := range turn.Reference.Chunks {
+			if chunk.ID != "" && seen[chunk.ID] {
+				continue
+			}
+			seen[chunk.ID] = true
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+func (cv *Conversation) State() ConversationState {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	state := cv.state
+	state.Turns = append([]ConversationTurn(nil), state.Turns...)
+	return state
+}
+
+func (cv *Conversation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(cv.State())
+}
+
+// Ask sends a question and records the answer in the history.
+func (cv *Conversation) Ask(ctx context.Context, question string) (*ConversationTurn, error) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	if err := cv.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+
+	turn := ConversationTurn{Question: question}
+	switch cv.state.Target {
+	case ConversationAgent:
+		resp, err := cv.client.RunAgent(ctx, cv.state.TargetID, question, cv.state.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Choices) > 0 {
+			turn.Answer = resp.Choices[0].Message.Content
+		}
+		turn.Reference = resp.Reference.chatReference()
+	default:
+		resp, err := cv.client.Converse(ctx, cv.state.TargetID, ConverseRequest{
+			Question:  question,
+			SessionID: cv.state.SessionID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		cv.state.SessionID = resp.SessionID
+		turn.Answer = resp.Answer
+		turn.Reference = resp.Reference
+	}
+
+	cv.state.Turns = append(cv.state.Turns, turn)
+	return &turn, nil
+}
+
+// AskStream sends a question and streams the answer as it is generated. The
+// turn is recorded in the history once the stream completes without error.
+func (cv *Conversation) AskStream(ctx context.Context, question string) (<-chan string, <-chan error) {
+	deltaChan := make(chan string)
+	errChan := make(chan error, 1)
+
+	cv.mu.Lock()
+	go func() {
+		defer cv.mu.Unlock()
+		defer close(deltaChan)
+		defer close(errChan)
+
+		if err := cv.ensureSession(ctx); err != nil {
+			errChan <- err
+			return
+		}
+
+		send := func(delta string) bool {
+			if delta == "" {
+				return true
+			}
+			select {
+			case deltaChan <- delta:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		turn := ConversationTurn{Question: question}
+		var err error
+		switch cv.state.Target {
+		case ConversationAgent:
+			respChan, agentErrChan := cv.client.RunAgentStream(ctx, cv.state.TargetID, question, cv.state.SessionID)
+			for resp := range respChan {
+				if len(resp.Reference.Chunks) > 0 {
+					turn.Reference = resp.Reference.chatReference()
+				}
+				if len(resp.Choices) == 0 {
+					continue
+				}
+				delta := resp.Choices[0].Delta.Content
+				turn.Answer += delta
+				if !send(delta) {
+					break
+				}
+			}
+			err = <-agentErrChan
+		default:
+			respChan, converseErrChan := cv.client.ConverseStream(ctx, cv.state.TargetID, ConverseRequest{
+				Question:  question,
+				SessionID: cv.state.SessionID,
+			})
+			for resp := range respChan {
+				cv.state.SessionID = resp.SessionID
+				turn.Answer = resp.Answer
+				if len(resp.Reference.Chunks) > 0 {
+					turn.Reference = resp.Reference
+				}
+				if !send(resp.Delta) {
+					break
+				}
+			}
+			err = <-converseErrChan
+		}
+
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			errChan <- err
+			return
+		}
+		cv.state.Turns = append(cv.state.Turns, turn)
+	}()
+
+	return deltaChan, errChan
+}
+
+// End deletes the conversation's session on the server. The history is kept;
+// a further question starts a new session.
+func (cv *Conversation) End(ctx context.Context) error {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	if cv.state.SessionID == "" {
+		return nil
+	}
+
+	var err error
+	switch cv.state.Target {
+	case ConversationAgent:
+		err = cv.client.DeleteAgentSessions(ctx, cv.state.TargetID, []string{cv.state.SessionID})
+	default:
+		err = cv.client.DeleteSession(ctx, cv.state.TargetID, cv.state.SessionID)
+	}
+	if err != nil {
+		return err
+	}
+
+	cv.state.SessionID = ""
+	return nil
+}
+
+// ensureSession creates the session before the first question. The caller
+// must hold cv.mu.
+func (cv *Conversation) ensureSession(ctx context.Context) error {
+	if cv.state.SessionID != "" {
+		return nil
+	}
+
+	switch cv.state.Target {
+	case ConversationAgent:
+		session, err := cv.client.CreateAgentSession(ctx, cv.state.TargetID, &CreateAgentSessionRequest{Inputs: cv.state.Inputs})
+		if err != nil {
+			return err
+		}
+		cv.state.SessionID = session.ID
+	default:
+		name := cv.state.SessionName
+		if name == "" {
+			name = "New session"
+		}
+		session, err := cv.client.CreateSession(ctx, cv.state.TargetID, CreateSessionRequest{Name: name})
+		if err != nil {
+			return err
+		}
+		cv.state.SessionID = session.ID
+	}
+	return nil
+}
+
+func (r ChatCompletionReference) chatReference() ChatReference {
+	ref := ChatReference{Total: len(r.Chunks)}
+	for _, chunk := range r.Chunks {
+		ref.Chunks = append(ref.Chunks, RetrievalChunk{
+			Chunk: Chunk{
+				ID:           chunk.ChunkID,
+				Content:      chunk.ContentWTKS,
+				DocumentID:   chunk.DocumentID,
+				DocumentName: chunk.DocumentName,
+				DatasetIDs:   chunk.Dataset,
+				ImageID:      chunk.Image,
+				Positions:    chunk.Positions,
+			},
+			ContentLTKS: chunk.ContentLTKS,
+			Similarity:  chunk.Similarity,
+		})
+	}
+	return ref
+}
@@ -0,0 +1,208 @@
+package ragflow_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+	"github.com/kevinroleke/ragflow-go/ragflowtest"
+)
+
+// conversationTarget sets up an assistant or agent on the fake server and
+// returns a function starting a conversation with it and one listing the IDs
+// of its sessions.
+type conversationTarget func(t *testing.T, client *ragflow.Client) (func() *ragflow.Conversation, func() []string)
+
+func assistantTarget(t *testing.T, client *ragflow.Client) (func() *ragflow.Conversation, func() []string) {
+	ctx := context.Background()
+	assistant, err := client.CreateAssistant(ctx, ragflow.CreateAssistantRequest{Name: "support"})
+	if err != nil {
+		t.Fatalf("CreateAssistant: %v", err)
+	}
+	start := func() *ragflow.Conversation {
+		return client.NewAssistantConversation(assistant.ID, &ragflow.ConversationOptions{SessionName: "test"})
+	}
+	sessions := func() []string {
+		list, err := client.ListSessions(ctx, assistant.ID, nil)
+		if err != nil {
+			t.Fatalf("ListSessions: %v", err)
+		}
+		var ids []string
+		for _, s := range list.Data.Items {
+			ids = append(ids, s.ID)
+		}
+		return ids
+	}
+	return start, sessions
+}
+
+func agentTarget(t *testing.T, client *ragflow.Client) (func() *ragflow.Conversation, func() []string) {
+	ctx := context.Background()
+	dsl, err := ragflow.NewDSLBuilder().
+		Component("begin", ragflow.BeginParams{Query: []ragflow.BeginQuery{{Key: "language", Type: "line"}}}).
+		Component("Answer:Talk", ragflow.AnswerParams{}).
+		Component("Generate:Reply", ragflow.GenerateParams{LLMID: "deepseek-chat"}).
+		Chain("begin", "Answer:Talk", "Generate:Reply", "Answer:Talk").
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	agent, err := client.CreateAgent(ctx, ragflow.CreateAgentRequest{Name: "helper", DSL: dsl})
+	if err != nil {
+		t.Fatalf("CreateAgent: %v", err)
+	}
+	start := func() *ragflow.Conversation {
+		return client.NewAgentConversation(agent.ID, &ragflow.ConversationOptions{
+			Inputs: map[string]ragflow.AgentInput{"language": ragflow.TextInput("English")},
+		})
+	}
+	sessions := func() []string {
+		list, err := client.ListAgentSessions(ctx, agent.ID, nil)
+		if err != nil {
+			t.Fatalf("ListAgentSessions: %v", err)
+		}
+		var ids []string
+		for _, s := range list.Data.Items {
+			ids = append(ids, s.ID)
+		}
+		return ids
+	}
+	return start, sessions
+}
+
+var conversationTargets = map[string]conversationTarget{
+	"assistant": assistantTarget,
+	"agent":     agentTarget,
+}
+
+func TestConversationAsk(t *testing.T) {
+	for name, setup := range conversationTargets {
+		t.Run(name, func(t *testing.T) {
+			srv := ragflowtest.NewServer(ragflowtest.WithReply(strings.ToUpper))
+			defer srv.Close()
+			client := srv.NewClient()
+			ctx := context.Background()
+			start, sessions := setup(t, client)
+
+			cv := start()
+			if cv.SessionID() != "" || len(sessions()) != 0 {
+				t.Fatal("session created before the first question")
+			}
+
+			turn, err := cv.Ask(ctx, "hello")
+			if err != nil || turn.Answer != "HELLO" {
+				t.Fatalf("Ask = %+v, %v", turn, err)
+			}
+			id := cv.SessionID()
+			if got := sessions(); id == "" || len(got) != 1 || got[0] != id {
+				t.Fatalf("session %q, server sessions %v", id, got)
+			}
+
+			if _, err := cv.Ask(ctx, "again"); err != nil {
+				t.Fatalf("second Ask: %v", err)
+			}
+			if cv.SessionID() != id || len(sessions()) != 1 {
+				t.Fatalf("second question used session %q, want %q", cv.SessionID(), id)
+			}
+			turns := cv.Turns()
+			if len(turns) != 2 || turns[0].Question != "hello" || turns[1].Answer != "AGAIN" {
+				t.Fatalf("Turns = %+v", turns)
+			}
+			if messages := cv.Messages(); len(messages) != 4 || messages[3].Role != "assistant" || messages[3].Content != "AGAIN" {
+				t.Fatalf("Messages = %+v", messages)
+			}
+
+			if err := cv.End(ctx); err != nil {
+				t.Fatalf("End: %v", err)
+			}
+			if cv.SessionID() != "" || len(sessions()) != 0 || len(cv.Turns()) != 2 {
+				t.Fatalf("after End: session %q, server sessions %v, %d turns", cv.SessionID(), sessions(), len(cv.Turns()))
+			}
+		})
+	}
+}
+
+func TestConversationAskStream(t *testing.T) {
+	for name, setup := range conversationTargets {
+		t.Run(name, func(t *testing.T) {
+			srv := ragflowtest.NewServer(ragflowtest.WithReply(strings.ToUpper))
+			defer srv.Close()
+			client := srv.NewClient()
+			start, sessions := setup(t, client)
+			cv := start()
+
+			deltas, errChan := cv.AskStream(context.Background(), "stream me an answer")
+			var answer string
+			for delta := range deltas {
+				answer += delta
+			}
+			if err := <-errChan; err != nil || answer != "STREAM ME AN ANSWER" {
+				t.Fatalf("AskStream = %q, %v", answer, err)
+			}
+			if turns := cv.Turns(); len(turns) != 1 || turns[0].Answer != answer {
+				t.Fatalf("Turns = %+v", turns)
+			}
+			if got := sessions(); len(got) != 1 || got[0] != cv.SessionID() {
+				t.Fatalf("server sessions %v, conversation session %q", got, cv.SessionID())
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			deltas, errChan = cv.AskStream(ctx, "cancel this one please")
+			<-deltas
+			cancel()
+			for range deltas {
+			}
+			if err := <-errChan; err == nil {
+				t.Fatal("cancelled AskStream reported no error")
+			}
+			if turns := cv.Turns(); len(turns) != 1 {
+				t.Fatalf("cancelled stream recorded a turn: %+v", turns)
+			}
+		})
+	}
+}
+
+func TestResumeConversation(t *testing.T) {
+	for name, setup := range conversationTargets {
+		t.Run(name, func(t *testing.T) {
+			srv := ragflowtest.NewServer()
+			defer srv.Close()
+			client := srv.NewClient()
+			ctx := context.Background()
+			start, sessions := setup(t, client)
+
+			cv := start()
+			if _, err := cv.Ask(ctx, "first"); err != nil {
+				t.Fatalf("Ask: %v", err)
+			}
+
+			data, err := json.Marshal(cv)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			var state ragflow.ConversationState
+			if err := json.Unmarshal(data, &state); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if state.SessionID != cv.SessionID() || len(state.Turns) != 1 {
+				t.Fatalf("State = %+v", state)
+			}
+
+			resumed := client.ResumeConversation(state)
+			if _, err := resumed.Ask(ctx, "second"); err != nil {
+				t.Fatalf("Ask after resuming: %v", err)
+			}
+			if resumed.SessionID() != cv.SessionID() || len(sessions()) != 1 {
+				t.Fatalf("resumed conversation used session %q, want %q", resumed.SessionID(), cv.SessionID())
+			}
+			if turns := resumed.Turns(); len(turns) != 2 || turns[1].Answer != "You said: second" {
+				t.Fatalf("Turns = %+v", turns)
+			}
+			if len(cv.Turns()) != 1 {
+				t.Fatal("resuming changed the original conversation")
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package ragflow
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Converse asks an assistant a question through RAGFlow's native chat API and
+// returns the answer with the chunks it cites. Pass the returned SessionID in
+// the next request to continue the conversation.
+func (c *Client) Converse(ctx context.Context, assistantID string, req ConverseRequest) (*ConverseResponse, error) {
+	endpoint := fmt.Sprintf("/api/v1/chats/%s/completions", assistantID)
+
+	req.Stream = false
+
+	httpReq, err := c.newRequest(ctx, http.MethodPost, endpoint, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp Response[ConverseResponse]
+	if err := c.do(httpReq, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Data.SessionID == "" {
+		resp.Data.SessionID = req.SessionID
+	}
+	return &resp.Data, nil
+}
+
+// ConverseStream is the streaming form of Converse. RAGFlow sends the whole
+// answer so far in every event; each response carries it in Answer and the
+// newly added text in Delta. The reference is usually only complete in the
+// last event.
+func (c *Client) ConverseStream(ctx context.Context, assistantID string, req ConverseRequest) (<-chan ConverseResponse, <-chan error) {
+	respChan := make(chan ConverseResponse)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(respChan)
+		defer close(errChan)
+
+		endpoint := fmt.Sprintf("/api/v1/chats/%s/completions", assistantID)
+
+		req.Stream = true
+
+		httpReq, err := c.newRequest(ctx, http.MethodPost, endpoint, req)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		httpReq.Header.Set("Accept", "text/event-stream")
+
+		resp, err := c.send(httpReq)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		defer resp.Body.Close()
+
+		sessionID := req.SessionID
+		var answer string
+
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				errChan <- fmt.Errorf("error reading stream: %w", err)
+				return
+			}
+			done := err == io.EOF
+
+			line = bytes.TrimSpace(line)
+			data, ok := bytes.CutPrefix(line, []byte("data:"))
+			if !ok {
+				// A non-streaming error response is sent as plain JSON.
+				data = line
+			}
+			data = bytes.TrimSpace(data)
+
+			if len(data) > 0 {
+				if err := streamError(httpReq, data); err != nil {
+					errChan <- err
+					return
+				}
+
+				var event Response[json.RawMessage]
+				if err := json.Unmarshal(data, &event); err != nil {
+					errChan <- fmt.Errorf("error unmarshaling stream data: %w", err)
+					return
+				}
+
+				// The stream ends with an event whose data is true.
+				if bytes.Equal(bytes.TrimSpace(event.Data), []byte("true")) {
+					return
+				}
+
+				var streamResp ConverseResponse
+				if err := json.Unmarshal(event.Data, &streamResp); err != nil {
+					errChan <- fmt.Errorf("error unmarshaling stream data: %w", err)
+					return
+				}
+
+				if streamResp.SessionID == "" {
+					streamResp.SessionID = sessionID
+				}
+				sessionID = streamResp.SessionID
+
+				if strings.HasPrefix(streamResp.Answer, answer) {
+					streamResp.Delta = streamResp.Answer[len(answer):]
+				} else {
+					streamResp.Delta = streamResp.Answer
+				}
+				answer = streamResp.Answer
+
+				select {
+				case respChan <- streamResp:
+				case <-ctx.Done():
+					errChan <- ctx.Err()
+					return
+				}
+			}
+
+			if done {
+				return
+			}
+		}
+	}()
+
+	return respChan, errChan
+}
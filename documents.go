@@ -3,10 +3,9 @@ package ragflow
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -20,106 +19,40 @@ func (c *Client) UploadDocument(ctx context.Context, datasetID, filePath string)
 	}
 	defer file.Close()
 
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-
-	part, err := writer.CreateFormFile("file", filepath.Base(filePath))
+	info, err := file.Stat()
 	if err != nil {
-		return nil, fmt.Errorf("error creating form file: %w", err)
-	}
-
-	if _, err := io.Copy(part, file); err != nil {
-		return nil, fmt.Errorf("error copying file: %w", err)
-	}
-
-	if err := writer.Close(); err != nil {
-		return nil, fmt.Errorf("error closing writer: %w", err)
+		return nil, fmt.Errorf("error reading file info: %w", err)
 	}
 
-	endpoint := fmt.Sprintf("/api/v1/datasets/%s/documents", datasetID)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+endpoint, &buf)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, c.handleErrorResponse(resp.StatusCode, bodyBytes)
-	}
-
-	var result ArrayResponse[Document]
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
-	}
-
-	if len(result.Data) == 0 {
-		return nil, fmt.Errorf("no documents returned")
-	}
-
-	return &result.Data[0], nil
+	return c.UploadDocumentReader(ctx, datasetID, filepath.Base(filePath), file, &UploadOptions{
+		Size: info.Size(),
+	})
 }
 
 func (c *Client) UploadDocumentFromBytes(ctx context.Context, datasetID, filename string, data []byte) (*Document, error) {
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-
-	part, err := writer.CreateFormFile("file", filename)
-	if err != nil {
-		return nil, fmt.Errorf("error creating form file: %w", err)
-	}
-
-	if _, err := part.Write(data); err != nil {
-		return nil, fmt.Errorf("error writing data: %w", err)
-	}
-
-	if err := writer.Close(); err != nil {
-		return nil, fmt.Errorf("error closing writer: %w", err)
-	}
-
-	endpoint := fmt.Sprintf("/api/v1/datasets/%s/documents", datasetID)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+endpoint, &buf)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return c.UploadDocumentReader(ctx, datasetID, filename, bytes.NewReader(data), &UploadOptions{
+		Size: int64(len(data)),
+	})
+}
 
-	resp, err := c.HTTPClient.Do(req)
+func (c *Client) GetDocument(ctx context.Context, datasetID, documentID string) (*Document, error) {
+	endpoint := fmt.Sprintf("/api/v1/datasets/%s/documents/%s", datasetID, documentID)
+	httpReq, err := c.newRequest(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, c.handleErrorResponse(resp.StatusCode, bodyBytes)
-	}
-
-	var result ArrayResponse[Document]
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+		return nil, err
 	}
 
-	if len(result.Data) == 0 {
-		return nil, fmt.Errorf("no documents returned")
+	var resp Response[Document]
+	if err := c.do(httpReq, &resp); err != nil {
+		return nil, err
 	}
 
-	return &result.Data[0], nil
+	return &resp.Data, nil
 }
 
-func (c *Client) GetDocument(ctx context.Context, datasetID, documentID string) (*Document, error) {
+func (c *Client) UpdateDocument(ctx context.Context, datasetID, documentID string, req UpdateDocumentRequest) (*Document, error) {
 	endpoint := fmt.Sprintf("/api/v1/datasets/%s/documents/%s", datasetID, documentID)
-	httpReq, err := c.newRequest(ctx, http.MethodGet, endpoint, nil)
+	httpReq, err := c.newRequest(ctx, http.MethodPut, endpoint, req)
 	if err != nil {
 		return nil, err
 	}
@@ -129,6 +62,24 @@ func (c *Client) GetDocument(ctx context.Context, datasetID, documentID string)
 		return nil, err
 	}
 
+	if req.Reparse {
+		if err := c.ParseDocuments(ctx, datasetID, []string{documentID}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Older servers do not return the updated document.
+	if resp.Data.ID == "" || req.Reparse {
+		list, err := c.ListDocuments(ctx, datasetID, &ListDocumentsOptions{ID: documentID})
+		if err != nil {
+			return nil, err
+		}
+		if len(list.Data.Items) == 0 {
+			return nil, fmt.Errorf("document %s not found after update: %w", documentID, ErrNotFound)
+		}
+		return &list.Data.Items[0], nil
+	}
+
 	return &resp.Data, nil
 }
 
@@ -146,6 +97,20 @@ func (c *Client) ParseDocuments(ctx context.Context, datasetID string, documentI
 	return c.do(httpReq, nil)
 }
 
+func (c *Client) StopParsingDocuments(ctx context.Context, datasetID string, documentIDs []string) error {
+	endpoint := fmt.Sprintf("/api/v1/datasets/%s/chunks", datasetID)
+	httpReq, err := c.newRequest(ctx, http.MethodDelete, endpoint, struct {
+		IDs []string `json:"document_ids"`
+	}{
+		IDs: documentIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.do(httpReq, nil)
+}
+
 func (c *Client) DeleteDocuments(ctx context.Context, datasetID string, documentIDs []string) error {
 	endpoint := fmt.Sprintf("/api/v1/datasets/%s/documents", datasetID)
 	httpReq, err := c.newRequest(ctx, http.MethodDelete, endpoint, struct {
@@ -215,17 +180,12 @@ func (c *Client) DownloadDocument(ctx context.Context, datasetID, documentID str
 		return nil, err
 	}
 
-	resp, err := c.HTTPClient.Do(httpReq)
+	resp, err := c.send(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, c.handleErrorResponse(resp.StatusCode, bodyBytes)
-	}
-
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("error reading response body: %w", err)
@@ -234,39 +194,108 @@ func (c *Client) DownloadDocument(ctx context.Context, datasetID, documentID str
 	return data, nil
 }
 
-func (c *Client) GetChunk(ctx context.Context, chunkID string) (*Chunk, error) {
-	endpoint := fmt.Sprintf("/api/v1/chunks/%s", chunkID)
-	httpReq, err := c.newRequest(ctx, http.MethodGet, endpoint, nil)
+func (c *Client) AddChunk(ctx context.Context, datasetID, documentID string, req AddChunkRequest) (*Chunk, error) {
+	endpoint := fmt.Sprintf("/api/v1/datasets/%s/documents/%s/chunks", datasetID, documentID)
+	httpReq, err := c.newRequest(ctx, http.MethodPost, endpoint, req)
 	if err != nil {
 		return nil, err
 	}
 
-	var resp Response[Chunk]
+	var resp Response[struct {
+		Chunk Chunk `json:"chunk"`
+	}]
 	if err := c.do(httpReq, &resp); err != nil {
 		return nil, err
 	}
 
-	return &resp.Data, nil
+	return &resp.Data.Chunk, nil
 }
 
-func (c *Client) UpdateChunk(ctx context.Context, chunkID string, req UpdateChunkRequest) (*Chunk, error) {
-	endpoint := fmt.Sprintf("/api/v1/chunks/%s", chunkID)
-	httpReq, err := c.newRequest(ctx, http.MethodPut, endpoint, req)
+const defaultAddChunkWorkers = 4
+
+type AddChunksOptions struct {
+	// Concurrency is the number of chunks created at once.
+	Concurrency int
+}
+
+// AddChunkResult reports the outcome for one request of AddChunks. Exactly one
+// of Chunk and Err is set.
+type AddChunkResult struct {
+	Request AddChunkRequest
+	Chunk   *Chunk
+	Err     error
+}
+
+// AddChunks creates many chunks in the document concurrently. The returned
+// results are in the order of reqs and the error joins the errors of all
+// failed requests.
+func (c *Client) AddChunks(ctx context.Context, datasetID, documentID string, reqs []AddChunkRequest, opts *AddChunksOptions) ([]AddChunkResult, error) {
+	workers := defaultAddChunkWorkers
+	if opts != nil && opts.Concurrency > 0 {
+		workers = opts.Concurrency
+	}
+
+	results := make([]AddChunkResult, len(reqs))
+	forEachConcurrent(ctx, len(reqs), workers, func(i int) {
+		results[i].Request = reqs[i]
+		results[i].Chunk, results[i].Err = c.AddChunk(ctx, datasetID, documentID, reqs[i])
+	}, func(i int, err error) {
+		results[i].Request = reqs[i]
+		results[i].Err = err
+	})
+
+	var errs []error
+	for i, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("chunk %d: %w", i, r.Err))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+func (c *Client) GetChunk(ctx context.Context, datasetID, documentID, chunkID string) (*Chunk, error) {
+	list, err := c.ListChunks(ctx, datasetID, documentID, &ListChunksOptions{ID: chunkID})
 	if err != nil {
 		return nil, err
 	}
 
-	var resp Response[Chunk]
-	if err := c.do(httpReq, &resp); err != nil {
-		return nil, err
+	for _, chunk := range list.Data.Items {
+		if chunk.ID == chunkID {
+			return &chunk, nil
+		}
 	}
 
-	return &resp.Data, nil
+	return nil, &APIError{
+		Code:       ErrorCodeNotFound,
+		Message:    fmt.Sprintf("chunk %s not found", chunkID),
+		StatusCode: http.StatusNotFound,
+		Method:     http.MethodGet,
+		Endpoint:   fmt.Sprintf("/api/v1/datasets/%s/documents/%s/chunks", datasetID, documentID),
+	}
+}
+
+func (c *Client) UpdateChunk(ctx context.Context, datasetID, documentID, chunkID string, req UpdateChunkRequest) error {
+	endpoint := fmt.Sprintf("/api/v1/datasets/%s/documents/%s/chunks/%s", datasetID, documentID, chunkID)
+	httpReq, err := c.newRequest(ctx, http.MethodPut, endpoint, req)
+	if err != nil {
+		return err
+	}
+
+	return c.do(httpReq, nil)
+}
+
+func (c *Client) DeleteChunk(ctx context.Context, datasetID, documentID, chunkID string) error {
+	return c.DeleteChunks(ctx, datasetID, documentID, []string{chunkID})
 }
 
-func (c *Client) DeleteChunk(ctx context.Context, chunkID string) error {
-	endpoint := fmt.Sprintf("/api/v1/chunks/%s", chunkID)
-	httpReq, err := c.newRequest(ctx, http.MethodDelete, endpoint, nil)
+func (c *Client) DeleteChunks(ctx context.Context, datasetID, documentID string, chunkIDs []string) error {
+	endpoint := fmt.Sprintf("/api/v1/datasets/%s/documents/%s/chunks", datasetID, documentID)
+	httpReq, err := c.newRequest(ctx, http.MethodDelete, endpoint, struct {
+		IDs []string `json:"chunk_ids"`
+	}{
+		IDs: chunkIDs,
+	})
 	if err != nil {
 		return err
 	}
@@ -275,16 +304,13 @@ func (c *Client) DeleteChunk(ctx context.Context, chunkID string) error {
 }
 
 type ListChunksOptions struct {
-	Page       int
-	PageSize   int
-	OrderBy    string
-	Desc       bool
-	Keywords   string
-	ID         string
-	DocumentID string
+	Page     int
+	PageSize int
+	Keywords string
+	ID       string
 }
 
-func (c *Client) ListChunks(ctx context.Context, datasetID string, opts *ListChunksOptions) (*ListResponse[Chunk], error) {
+func (c *Client) ListChunks(ctx context.Context, datasetID, documentID string, opts *ListChunksOptions) (*ChunksList, error) {
 	params := make(map[string]string)
 
 	if opts != nil {
@@ -294,31 +320,22 @@ func (c *Client) ListChunks(ctx context.Context, datasetID string, opts *ListChu
 		if opts.PageSize > 0 {
 			params["page_size"] = strconv.Itoa(opts.PageSize)
 		}
-		if opts.OrderBy != "" {
-			params["orderby"] = opts.OrderBy
-		}
-		if opts.Desc {
-			params["desc"] = "true"
-		}
 		if opts.Keywords != "" {
 			params["keywords"] = opts.Keywords
 		}
 		if opts.ID != "" {
 			params["id"] = opts.ID
 		}
-		if opts.DocumentID != "" {
-			params["document_id"] = opts.DocumentID
-		}
 	}
 
-	endpoint := fmt.Sprintf("/api/v1/datasets/%s/chunks", datasetID)
+	endpoint := fmt.Sprintf("/api/v1/datasets/%s/documents/%s/chunks", datasetID, documentID)
 	url := c.buildURL(endpoint, params)
 	httpReq, err := c.newRequest(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	var resp ListResponse[Chunk]
+	var resp ChunksList
 	if err := c.do(httpReq, &resp); err != nil {
 		return nil, err
 	}
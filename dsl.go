@@ -0,0 +1,326 @@
+package ragflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// DSL is an agent's canvas: its components, the graph the web UI draws of
+// them, and the state of the last run.
+//
+// The DSL types keep the JSON members they have no field for, so a canvas
+// fetched with GetAgent can be modified and sent back with UpdateAgent
+// without losing anything this package does not model.
+type DSL struct {
+	Components map[string]*Component  `json:"components"`
+	Graph      Graph                  `json:"graph"`
+	Globals    map[string]interface{} `json:"globals,omitempty"`
+	History    []HistoryEntry         `json:"history"`
+	Path       []json.RawMessage      `json:"path"`
+	Answer     []string               `json:"answer"`
+	Messages   []json.RawMessage      `json:"messages"`
+	Reference  []json.RawMessage      `json:"reference"`
+
+	raw map[string]json.RawMessage
+}
+
+func (d *DSL) UnmarshalJSON(data []byte) error {
+	type plain DSL
+	return decodeObject(data, (*plain)(d), &d.raw)
+}
+
+func (d DSL) MarshalJSON() ([]byte, error) {
+	type plain DSL
+	return encodeObject(plain(d), d.raw)
+}
+
+// Component is a node of the canvas and its connections. Downstream and
+// Upstream list the IDs of the components it passes control to and receives
+// it from.
+type Component struct {
+	Obj        ComponentObject `json:"obj"`
+	Downstream []string        `json:"downstream"`
+	Upstream   []string        `json:"upstream"`
+	ParentID   string          `json:"parent_id,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+func (c *Component) UnmarshalJSON(data []byte) error {
+	type plain Component
+	return decodeObject(data, (*plain)(c), &c.raw)
+}
+
+func (c Component) MarshalJSON() ([]byte, error) {
+	type plain Component
+	return encodeObject(plain(c), c.raw)
+}
+
+// ComponentObject holds a component's parameters. Params has the concrete
+// type registered for ComponentName, such as *RetrievalParams, or
+// *RawParams for component types this package does not know and for
+// parameters that do not fit their type, such as a number sent as a string.
+type ComponentObject struct {
+	ComponentName string
+	Params        ComponentParams
+
+	raw map[string]json.RawMessage
+}
+
+type componentObjectJSON struct {
+	ComponentName string          `json:"component_name"`
+	Params        json.RawMessage `json:"params"`
+}
+
+func (o *ComponentObject) UnmarshalJSON(data []byte) error {
+	var obj componentObjectJSON
+	if err := decodeObject(data, &obj, &o.raw); err != nil {
+		return err
+	}
+
+	o.ComponentName = obj.ComponentName
+	o.Params = newComponentParams(obj.ComponentName)
+	if len(obj.Params) == 0 || bytes.Equal(obj.Params, []byte("null")) {
+		return nil
+	}
+	if err := json.Unmarshal(obj.Params, o.Params); err != nil {
+		o.Params = &RawParams{Name: obj.ComponentName, JSON: append(json.RawMessage(nil), obj.Params...)}
+	}
+	return nil
+}
+
+// name returns the component type, taken from Params if ComponentName is
+// not set.
+func (o ComponentObject) name() string {
+	if o.ComponentName == "" && o.Params != nil {
+		return o.Params.ComponentName()
+	}
+	return o.ComponentName
+}
+
+func (o ComponentObject) MarshalJSON() ([]byte, error) {
+	name := o.name()
+
+	params := json.RawMessage("{}")
+	if o.Params != nil {
+		var err error
+		if params, err = json.Marshal(o.Params); err != nil {
+			return nil, err
+		}
+	}
+
+	return encodeObject(componentObjectJSON{ComponentName: name, Params: params}, o.raw)
+}
+
+// HistoryEntry is a message of the conversation history kept in the DSL,
+// encoded by RAGFlow as a [role, content] pair.
+//
+// Entries in another form, such as content that is not a string, are kept
+// as they were and encoded unchanged; Content then holds the content's JSON.
+type HistoryEntry struct {
+	Role    string
+	Content string
+
+	raw json.RawMessage
+}
+
+func (h *HistoryEntry) UnmarshalJSON(data []byte) error {
+	var pair []string
+	if err := json.Unmarshal(data, &pair); err == nil && len(pair) == 2 {
+		h.Role, h.Content, h.raw = pair[0], pair[1], nil
+		return nil
+	}
+
+	if !json.Valid(data) {
+		return fmt.Errorf("invalid history entry: %s", data)
+	}
+	*h = HistoryEntry{raw: append(json.RawMessage(nil), data...)}
+	var elems []json.RawMessage
+	if json.Unmarshal(data, &elems) == nil && len(elems) == 2 {
+		json.Unmarshal(elems[0], &h.Role)
+		if json.Unmarshal(elems[1], &h.Content) != nil {
+			h.Content = string(elems[1])
+		}
+	}
+	return nil
+}
+
+func (h HistoryEntry) MarshalJSON() ([]byte, error) {
+	if h.raw != nil {
+		return h.raw, nil
+	}
+	return json.Marshal([]string{h.Role, h.Content})
+}
+
+// Graph is the web UI's drawing of the canvas.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+
+	raw map[string]json.RawMessage
+}
+
+func (g *Graph) UnmarshalJSON(data []byte) error {
+	type plain Graph
+	return decodeObject(data, (*plain)(g), &g.raw)
+}
+
+func (g Graph) MarshalJSON() ([]byte, error) {
+	type plain Graph
+	return encodeObject(plain(g), g.raw)
+}
+
+type GraphNode struct {
+	ID             string        `json:"id"`
+	Type           string        `json:"type"`
+	Position       GraphPosition `json:"position"`
+	Data           GraphNodeData `json:"data"`
+	SourcePosition string        `json:"sourcePosition,omitempty"`
+	TargetPosition string        `json:"targetPosition,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+func (n *GraphNode) UnmarshalJSON(data []byte) error {
+	type plain GraphNode
+	return decodeObject(data, (*plain)(n), &n.raw)
+}
+
+func (n GraphNode) MarshalJSON() ([]byte, error) {
+	type plain GraphNode
+	return encodeObject(plain(n), n.raw)
+}
+
+type GraphPosition struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type GraphNodeData struct {
+	Label string                 `json:"label"`
+	Name  string                 `json:"name"`
+	Form  map[string]interface{} `json:"form,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+func (d *GraphNodeData) UnmarshalJSON(data []byte) error {
+	type plain GraphNodeData
+	return decodeObject(data, (*plain)(d), &d.raw)
+}
+
+func (d GraphNodeData) MarshalJSON() ([]byte, error) {
+	type plain GraphNodeData
+	return encodeObject(plain(d), d.raw)
+}
+
+type GraphEdge struct {
+	ID           string `json:"id"`
+	Source       string `json:"source"`
+	Target       string `json:"target"`
+	SourceHandle string `json:"sourceHandle,omitempty"`
+	TargetHandle string `json:"targetHandle,omitempty"`
+	Type         string `json:"type,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+func (e *GraphEdge) UnmarshalJSON(data []byte) error {
+	type plain GraphEdge
+	return decodeObject(data, (*plain)(e), &e.raw)
+}
+
+func (e GraphEdge) MarshalJSON() ([]byte, error) {
+	type plain GraphEdge
+	return encodeObject(plain(e), e.raw)
+}
+
+// decodeObject decodes a JSON object into v, a pointer to a struct, and keeps
+// all of its members in raw for encodeObject.
+func decodeObject(data []byte, v interface{}, raw *map[string]json.RawMessage) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	*raw = nil
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+	return json.Unmarshal(data, raw)
+}
+
+// encodeObject encodes v, a struct, together with the members of raw that v
+// has no field for. Empty omitempty fields are still written if the decoded
+// object had them, so that explicit zero values survive a round trip.
+func encodeObject(v interface{}, raw map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(raw) == 0 {
+		return data, err
+	}
+
+	var out map[string]json.RawMessage
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+
+	rv := reflect.ValueOf(v)
+	known := make(map[string]bool)
+	for _, f := range jsonFields(rv.Type()) {
+		known[f.name] = true
+		if _, written := out[f.name]; written {
+			continue
+		}
+		if _, decoded := raw[f.name]; !decoded {
+			continue
+		}
+		value, err := json.Marshal(rv.Field(f.index).Interface())
+		if err != nil {
+			return nil, err
+		}
+		out[f.name] = value
+	}
+
+	for name, value := range raw {
+		if !known[name] {
+			out[name] = value
+		}
+	}
+	return json.Marshal(out)
+}
+
+type jsonField struct {
+	name  string
+	index int
+}
+
+var jsonFieldCache sync.Map
+
+// jsonFields lists the exported fields of a struct type by their JSON names.
+func jsonFields(t reflect.Type) []jsonField {
+	if cached, ok := jsonFieldCache.Load(t); ok {
+		return cached.([]jsonField)
+	}
+
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{name: name, index: i})
+	}
+
+	jsonFieldCache.Store(t, fields)
+	return fields
+}
@@ -0,0 +1,251 @@
+package ragflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// DSLBuilder assembles an agent canvas. The methods can be chained; mistakes
+// such as duplicate IDs or edges to unknown components are reported by Build.
+//
+//	dsl, err := ragflow.NewDSLBuilder().
+//		Component("begin", ragflow.BeginParams{Prologue: "Hi! How can I help?"}).
+//		Component("Answer:Talk", ragflow.AnswerParams{}).
+//		Component("Retrieval:Docs", ragflow.RetrievalParams{DatasetIDs: []string{datasetID}}).
+//		Component("Generate:Reply", ragflow.GenerateParams{LLMID: "deepseek-chat", Prompt: prompt}).
+//		Chain("begin", "Answer:Talk", "Retrieval:Docs", "Generate:Reply", "Answer:Talk").
+//		Build()
+type DSLBuilder struct {
+	ids     []string
+	params  map[string]ComponentParams
+	edges   []dslEdge
+	globals map[string]interface{}
+	errs    []error
+}
+
+type dslEdge struct {
+	from, to string
+	handle   string
+}
+
+func NewDSLBuilder() *DSLBuilder {
+	return &DSLBuilder{params: make(map[string]ComponentParams)}
+}
+
+// Component adds a component. The edges implied by the targets of Categorize
+// and Switch params are added by Build.
+func (b *DSLBuilder) Component(id string, params ComponentParams) *DSLBuilder {
+	switch {
+	case id == "":
+		b.errs = append(b.errs, errors.New("component ID is empty"))
+	case params == nil:
+		b.errs = append(b.errs, fmt.Errorf("component %s has no params", id))
+	case b.params[id] != nil:
+		b.errs = append(b.errs, fmt.Errorf("duplicate component ID %s", id))
+	default:
+		b.ids = append(b.ids, id)
+		b.params[id] = params
+	}
+	return b
+}
+
+// Edge passes control from one component to another.
+func (b *DSLBuilder) Edge(from, to string) *DSLBuilder {
+	b.edges = append(b.edges, dslEdge{from: from, to: to})
+	return b
+}
+
+// Chain adds edges between consecutive components.
+func (b *DSLBuilder) Chain(ids ...string) *DSLBuilder {
+	for i := 1; i < len(ids); i++ {
+		b.Edge(ids[i-1], ids[i])
+	}
+	return b
+}
+
+// Global sets a global variable of the canvas.
+func (b *DSLBuilder) Global(key string, value interface{}) *DSLBuilder {
+	if b.globals == nil {
+		b.globals = make(map[string]interface{})
+	}
+	b.globals[key] = value
+	return b
+}
+
+// Build returns the canvas, with the graph drawn for the web UI.
+func (b *DSLBuilder) Build() (*DSL, error) {
+	errs := append([]error(nil), b.errs...)
+
+	edges := append([]dslEdge(nil), b.edges...)
+	for _, id := range b.ids {
+		edges = append(edges, impliedEdges(id, b.params[id])...)
+	}
+
+	dsl := &DSL{
+		Components: make(map[string]*Component, len(b.ids)),
+		Globals:    b.globals,
+		History:    []HistoryEntry{},
+		Path:       []json.RawMessage{},
+		Answer:     []string{},
+		Messages:   []json.RawMessage{},
+		Reference:  []json.RawMessage{},
+	}
+	for _, id := range b.ids {
+		dsl.Components[id] = &Component{
+			Obj:        ComponentObject{ComponentName: b.params[id].ComponentName(), Params: b.params[id]},
+			Downstream: []string{},
+			Upstream:   []string{},
+		}
+	}
+
+	linked := make(map[[2]string]bool)
+	drawn := make(map[dslEdge]bool)
+	graphEdges := []GraphEdge{}
+	for _, e := range edges {
+		from, to := dsl.Components[e.from], dsl.Components[e.to]
+		if from == nil || to == nil {
+			missing := e.from
+			if from != nil {
+				missing = e.to
+			}
+			errs = append(errs, fmt.Errorf("edge %s -> %s: unknown component %s", e.from, e.to, missing))
+			continue
+		}
+
+		if link := [2]string{e.from, e.to}; !linked[link] {
+			linked[link] = true
+			from.Downstream = append(from.Downstream, e.to)
+			to.Upstream = append(to.Upstream, e.from)
+		}
+
+		if e.handle == "" {
+			e.handle = "b"
+		}
+		if drawn[e] {
+			continue
+		}
+		drawn[e] = true
+		graphEdges = append(graphEdges, GraphEdge{
+			ID:           "xy-edge__" + e.from + e.handle + "-" + e.to + "c",
+			Source:       e.from,
+			Target:       e.to,
+			SourceHandle: e.handle,
+			TargetHandle: "c",
+			Type:         "buttonEdge",
+		})
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	nodes, err := b.graphNodes(dsl)
+	if err != nil {
+		return nil, err
+	}
+	dsl.Graph = Graph{Nodes: nodes, Edges: graphEdges}
+	return dsl, nil
+}
+
+// impliedEdges returns the edges to the targets named in a component's
+// params. Their handles match the ones the web UI gives the outputs of
+// Categorize and Switch nodes.
+func impliedEdges(id string, params ComponentParams) []dslEdge {
+	var edges []dslEdge
+	switch p := paramsPointer(params).(type) {
+	case *CategorizeParams:
+		names := make([]string, 0, len(p.Categories))
+		for name := range p.Categories {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if to := p.Categories[name].To; to != "" {
+				edges = append(edges, dslEdge{from: id, to: to, handle: name})
+			}
+		}
+	case *SwitchParams:
+		for i, condition := range p.Conditions {
+			if condition.To != "" {
+				edges = append(edges, dslEdge{from: id, to: condition.To, handle: "Case " + strconv.Itoa(i+1)})
+			}
+		}
+		if p.End != "" {
+			edges = append(edges, dslEdge{from: id, to: p.End, handle: "end_cpn_id"})
+		}
+	}
+	return edges
+}
+
+var graphNodeTypes = map[string]string{
+	"Begin":           "beginNode",
+	"Answer":          "logicNode",
+	"Retrieval":       "retrievalNode",
+	"Generate":        "generateNode",
+	"Categorize":      "categorizeNode",
+	"Switch":          "switchNode",
+	"Message":         "messageNode",
+	"RewriteQuestion": "rewriteNode",
+	"KeywordExtract":  "keywordNode",
+}
+
+// graphNodes lays the components out in columns by their distance from the
+// components without upstream.
+func (b *DSLBuilder) graphNodes(dsl *DSL) ([]GraphNode, error) {
+	depth := make(map[string]int, len(b.ids))
+	var queue []string
+	for _, id := range b.ids {
+		if len(dsl.Components[id].Upstream) == 0 {
+			depth[id] = 0
+			queue = append(queue, id)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range dsl.Components[id].Downstream {
+			if _, ok := depth[next]; !ok {
+				depth[next] = depth[id] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	rows := make(map[int]int)
+	nodes := make([]GraphNode, 0, len(b.ids))
+	for _, id := range b.ids {
+		col, ok := depth[id]
+		if !ok {
+			// Only reachable through a cycle; put it after the others.
+			col = len(b.ids)
+		}
+		row := rows[col]
+		rows[col]++
+
+		obj := dsl.Components[id].Obj
+		data, err := json.Marshal(obj.Params)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding %s params: %w", id, err)
+		}
+		var form map[string]interface{}
+		if err := json.Unmarshal(data, &form); err != nil {
+			return nil, fmt.Errorf("error encoding %s params: %w", id, err)
+		}
+
+		nodeType, ok := graphNodeTypes[obj.ComponentName]
+		if !ok {
+			nodeType = "ragNode"
+		}
+		nodes = append(nodes, GraphNode{
+			ID:             id,
+			Type:           nodeType,
+			Position:       GraphPosition{X: float64(col * 300), Y: float64(row * 150)},
+			Data:           GraphNodeData{Label: obj.ComponentName, Name: id, Form: form},
+			SourcePosition: "right",
+			TargetPosition: "left",
+		})
+	}
+	return nodes, nil
+}
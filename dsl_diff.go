@@ -0,0 +1,263 @@
+package ragflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DSLEdge is a connection from one component to another.
+type DSLEdge struct {
+	From string
+	To   string
+}
+
+// DSLValueChange is a changed value of a component or of the globals. Key is
+// "component_name", "parent_id" or "params.<name>" for components and the
+// variable name for globals. Old or New is nil if the value is absent.
+type DSLValueChange struct {
+	Key string
+	Old json.RawMessage
+	New json.RawMessage
+}
+
+type DSLComponentChange struct {
+	ID      string
+	Changes []DSLValueChange
+}
+
+// DSLDiff lists the differences between two canvases that affect how the
+// agent runs. Graph layout and the state of the last run are not compared.
+type DSLDiff struct {
+	AddedComponents   []string
+	RemovedComponents []string
+	ChangedComponents []DSLComponentChange
+	AddedEdges        []DSLEdge
+	RemovedEdges      []DSLEdge
+	Globals           []DSLValueChange
+}
+
+func (d *DSLDiff) Empty() bool {
+	return len(d.AddedComponents) == 0 && len(d.RemovedComponents) == 0 &&
+		len(d.ChangedComponents) == 0 && len(d.AddedEdges) == 0 &&
+		len(d.RemovedEdges) == 0 && len(d.Globals) == 0
+}
+
+// String formats the diff one change per line, prefixed with +, - or ~.
+func (d *DSLDiff) String() string {
+	var b strings.Builder
+	for _, id := range d.AddedComponents {
+		fmt.Fprintf(&b, "+ component %s\n", id)
+	}
+	for _, id := range d.RemovedComponents {
+		fmt.Fprintf(&b, "- component %s\n", id)
+	}
+	for _, c := range d.ChangedComponents {
+		for _, v := range c.Changes {
+			fmt.Fprintf(&b, "~ %s %s: %s\n", c.ID, v.Key, formatValueChange(v))
+		}
+	}
+	for _, e := range d.AddedEdges {
+		fmt.Fprintf(&b, "+ edge %s -> %s\n", e.From, e.To)
+	}
+	for _, e := range d.RemovedEdges {
+		fmt.Fprintf(&b, "- edge %s -> %s\n", e.From, e.To)
+	}
+	for _, v := range d.Globals {
+		fmt.Fprintf(&b, "~ global %s: %s\n", v.Key, formatValueChange(v))
+	}
+	return b.String()
+}
+
+func formatValueChange(v DSLValueChange) string {
+	switch {
+	case v.Old == nil:
+		return "added " + string(v.New)
+	case v.New == nil:
+		return "removed " + string(v.Old)
+	}
+	return string(v.Old) + " -> " + string(v.New)
+}
+
+// DiffDSL compares two canvases. Either may be nil, which compares as an
+// empty canvas.
+func DiffDSL(old, new *DSL) (*DSLDiff, error) {
+	a, err := snapshotDSL(old)
+	if err != nil {
+		return nil, err
+	}
+	b, err := snapshotDSL(new)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &DSLDiff{}
+	for _, id := range unionKeys(a.components, b.components) {
+		ca, cb := a.components[id], b.components[id]
+		switch {
+		case ca == nil:
+			diff.AddedComponents = append(diff.AddedComponents, id)
+		case cb == nil:
+			diff.RemovedComponents = append(diff.RemovedComponents, id)
+		default:
+			if changes := diffValues(ca, cb); len(changes) > 0 {
+				diff.ChangedComponents = append(diff.ChangedComponents, DSLComponentChange{ID: id, Changes: changes})
+			}
+		}
+	}
+
+	for _, e := range sortedEdges(b.edges) {
+		if !a.edges[e] {
+			diff.AddedEdges = append(diff.AddedEdges, e)
+		}
+	}
+	for _, e := range sortedEdges(a.edges) {
+		if !b.edges[e] {
+			diff.RemovedEdges = append(diff.RemovedEdges, e)
+		}
+	}
+
+	diff.Globals = diffValues(a.globals, b.globals)
+	return diff, nil
+}
+
+// dslSnapshot holds the parts of a canvas that are compared, as canonical
+// JSON so that equal values compare equal byte for byte.
+type dslSnapshot struct {
+	components map[string]map[string]json.RawMessage
+	edges      map[DSLEdge]bool
+	globals    map[string]json.RawMessage
+}
+
+func snapshotDSL(d *DSL) (*dslSnapshot, error) {
+	s := &dslSnapshot{
+		components: make(map[string]map[string]json.RawMessage),
+		edges:      make(map[DSLEdge]bool),
+		globals:    make(map[string]json.RawMessage),
+	}
+	if d == nil {
+		return s, nil
+	}
+
+	for id, c := range d.Components {
+		if c == nil {
+			continue
+		}
+		values, err := componentValues(c)
+		if err != nil {
+			return nil, fmt.Errorf("error comparing component %s: %w", id, err)
+		}
+		s.components[id] = values
+
+		for _, to := range c.Downstream {
+			s.edges[DSLEdge{From: id, To: to}] = true
+		}
+		for _, e := range impliedEdges(id, c.Obj.Params) {
+			s.edges[DSLEdge{From: id, To: e.to}] = true
+		}
+	}
+
+	for key, value := range d.Globals {
+		data, err := canonicalJSON(value)
+		if err != nil {
+			return nil, fmt.Errorf("error comparing global %s: %w", key, err)
+		}
+		s.globals[key] = data
+	}
+	return s, nil
+}
+
+// componentValues flattens a component into the keys of DSLValueChange.
+func componentValues(c *Component) (map[string]json.RawMessage, error) {
+	values := make(map[string]json.RawMessage)
+	name, err := json.Marshal(c.Obj.name())
+	if err != nil {
+		return nil, err
+	}
+	values["component_name"] = name
+	if c.ParentID != "" {
+		if values["parent_id"], err = json.Marshal(c.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	var params interface{} = map[string]interface{}{}
+	if c.Obj.Params != nil {
+		params = c.Obj.Params
+	}
+	data, err := canonicalJSON(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var members map[string]json.RawMessage
+	if json.Unmarshal(data, &members) != nil {
+		// Params that are not an object are compared as a whole.
+		values["params"] = data
+		return values, nil
+	}
+	for key, value := range members {
+		values["params."+key] = value
+	}
+	return values, nil
+}
+
+func diffValues(a, b map[string]json.RawMessage) []DSLValueChange {
+	var changes []DSLValueChange
+	for _, key := range unionKeys(a, b) {
+		va, vb := a[key], b[key]
+		if !bytes.Equal(va, vb) {
+			changes = append(changes, DSLValueChange{Key: key, Old: va, New: vb})
+		}
+	}
+	return changes
+}
+
+// canonicalJSON encodes v with the members of all objects sorted.
+func canonicalJSON(v interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
+
+// unionKeys returns the keys of all maps, sorted.
+func unionKeys[V any](maps ...map[string]V) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedEdges(edges map[DSLEdge]bool) []DSLEdge {
+	sorted := make([]DSLEdge, 0, len(edges))
+	for e, ok := range edges {
+		if ok {
+			sorted = append(sorted, e)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].From != sorted[j].From {
+			return sorted[i].From < sorted[j].From
+		}
+		return sorted[i].To < sorted[j].To
+	})
+	return sorted
+}
@@ -0,0 +1,405 @@
+package ragflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrDSLConflict = errors.New("ragflow: conflicting agent DSL changes")
+
+// DSLConflict is a value local and remote changed in different ways. Key
+// names the value as in DSLValueChange and is empty if the whole component
+// conflicts, because one side removed it and the other changed it. The
+// values are nil where absent.
+type DSLConflict struct {
+	ComponentID string
+	Key         string
+	Base        json.RawMessage
+	Local       json.RawMessage
+	Remote      json.RawMessage
+}
+
+func (c DSLConflict) String() string {
+	where := "component " + c.ComponentID
+	switch {
+	case c.ComponentID == "":
+		where = "global " + c.Key
+	case c.Key != "":
+		where = c.ComponentID + " " + c.Key
+	}
+	return fmt.Sprintf("%s: base %s, local %s, remote %s", where, conflictValue(c.Base), conflictValue(c.Local), conflictValue(c.Remote))
+}
+
+func conflictValue(v json.RawMessage) string {
+	if v == nil {
+		return "absent"
+	}
+	return string(v)
+}
+
+// DSLMergeError reports the conflicts that kept UpdateAgentDSL from saving.
+// It matches ErrDSLConflict with errors.Is.
+type DSLMergeError struct {
+	Conflicts []DSLConflict
+}
+
+func (e *DSLMergeError) Error() string {
+	lines := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		lines[i] = c.String()
+	}
+	return "conflicting agent DSL changes: " + strings.Join(lines, "; ")
+}
+
+func (e *DSLMergeError) Is(target error) bool {
+	return target == ErrDSLConflict
+}
+
+// MergeDSL combines the changes local and remote made to base, component
+// param by component param. Conflicting changes are resolved in favour of
+// local and reported. The layout, the state of the last run and anything
+// else not compared by DiffDSL is taken from local, with the graph nodes and
+// edges of components merged in from remote.
+func MergeDSL(base, local, remote *DSL) (*DSL, []DSLConflict, error) {
+	sb, err := snapshotDSL(base)
+	if err != nil {
+		return nil, nil, err
+	}
+	sl, err := snapshotDSL(local)
+	if err != nil {
+		return nil, nil, err
+	}
+	sr, err := snapshotDSL(remote)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged, err := copyDSL(local)
+	if err != nil {
+		return nil, nil, err
+	}
+	theirs, err := copyDSL(remote)
+	if err != nil {
+		return nil, nil, err
+	}
+	if merged.Components == nil {
+		merged.Components = make(map[string]*Component)
+	}
+
+	var conflicts []DSLConflict
+	changed := make(map[string]bool)
+	ids := unionKeys(sb.components, sl.components, sr.components)
+	for _, id := range ids {
+		b, l, r := sb.components[id], sl.components[id], sr.components[id]
+		switch {
+		case l == nil && r == nil:
+		case l == nil:
+			if b == nil {
+				merged.Components[id] = theirs.Components[id]
+			} else if !equalValues(b, r) {
+				conflicts = append(conflicts, componentConflict(id, base, local, remote))
+			}
+		case r == nil:
+			if b == nil {
+				break
+			}
+			if equalValues(b, l) {
+				delete(merged.Components, id)
+			} else {
+				conflicts = append(conflicts, componentConflict(id, base, local, remote))
+			}
+		default:
+			values, keys := mergeValues(b, l, r)
+			for _, key := range keys {
+				conflicts = append(conflicts, DSLConflict{ComponentID: id, Key: key, Base: b[key], Local: l[key], Remote: r[key]})
+			}
+			if !equalValues(values, l) {
+				if err := setComponentValues(merged.Components[id], values); err != nil {
+					return nil, nil, fmt.Errorf("error merging component %s: %w", id, err)
+				}
+				changed[id] = true
+			}
+		}
+	}
+
+	edges := make(map[DSLEdge]bool)
+	for _, e := range mergedEdgeCandidates(sb, sl, sr) {
+		inB, inL, inR := sb.edges[e], sl.edges[e], sr.edges[e]
+		keep := inL
+		if inL != inR && inL == inB {
+			keep = inR
+		}
+		if keep && merged.Components[e.From] != nil && merged.Components[e.To] != nil {
+			edges[e] = true
+		}
+	}
+	relink(merged, edges)
+
+	globals, keys := mergeValues(sb.globals, sl.globals, sr.globals)
+	for _, key := range keys {
+		conflicts = append(conflicts, DSLConflict{Key: key, Base: sb.globals[key], Local: sl.globals[key], Remote: sr.globals[key]})
+	}
+	if !equalValues(globals, sl.globals) {
+		merged.Globals = make(map[string]interface{}, len(globals))
+		for key, value := range globals {
+			var v interface{}
+			if err := json.Unmarshal(value, &v); err != nil {
+				return nil, nil, fmt.Errorf("error merging global %s: %w", key, err)
+			}
+			merged.Globals[key] = v
+		}
+	}
+
+	if err := mergeGraph(merged, theirs, edges, changed, ids); err != nil {
+		return nil, nil, err
+	}
+	return merged, conflicts, nil
+}
+
+// UpdateAgentDSL saves dsl, an edited copy of base.DSL, as the canvas of the
+// agent base was fetched as. If the agent's UpdateTime or canvas changed
+// since, the edits are merged into the current canvas with MergeDSL; if they
+// conflict, nothing is saved and the error is a *DSLMergeError. RAGFlow has
+// no conditional update, so a change saved between the check and the update
+// is still overwritten.
+func (c *Client) UpdateAgentDSL(ctx context.Context, base *Agent, dsl *DSL) (*Agent, error) {
+	current, err := c.GetAgent(ctx, base.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// UpdateTime has a resolution of a second, so compare the canvases too.
+	diff, err := DiffDSL(base.DSL, current.DSL)
+	if err != nil {
+		return nil, err
+	}
+	if !current.UpdateTime.Equal(base.UpdateTime.Time) || !diff.Empty() {
+		merged, conflicts, err := MergeDSL(base.DSL, dsl, current.DSL)
+		if err != nil {
+			return nil, err
+		}
+		if len(conflicts) > 0 {
+			return nil, &DSLMergeError{Conflicts: conflicts}
+		}
+		dsl = merged
+	}
+
+	return c.UpdateAgent(ctx, base.ID, UpdateAgentRequest{DSL: dsl})
+}
+
+// mergeValues merges two sets of changes to base key by key. Keys changed
+// differently on both sides keep the local value and are returned as
+// conflicts.
+func mergeValues(base, local, remote map[string]json.RawMessage) (map[string]json.RawMessage, []string) {
+	merged := make(map[string]json.RawMessage)
+	var conflicts []string
+	for _, key := range unionKeys(base, local, remote) {
+		b, l, r := base[key], local[key], remote[key]
+		v := l
+		switch {
+		case equalValue(l, r), equalValue(r, b):
+		case equalValue(l, b):
+			v = r
+		default:
+			conflicts = append(conflicts, key)
+		}
+		if v != nil {
+			merged[key] = v
+		}
+	}
+	return merged, conflicts
+}
+
+func equalValue(a, b json.RawMessage) bool {
+	return (a == nil) == (b == nil) && bytes.Equal(a, b)
+}
+
+func equalValues(a, b map[string]json.RawMessage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, va := range a {
+		if vb, ok := b[key]; !ok || !bytes.Equal(va, vb) {
+			return false
+		}
+	}
+	return true
+}
+
+func componentConflict(id string, base, local, remote *DSL) DSLConflict {
+	encode := func(d *DSL) json.RawMessage {
+		if d == nil || d.Components[id] == nil {
+			return nil
+		}
+		data, err := canonicalJSON(d.Components[id])
+		if err != nil {
+			return nil
+		}
+		return data
+	}
+	return DSLConflict{ComponentID: id, Base: encode(base), Local: encode(local), Remote: encode(remote)}
+}
+
+// setComponentValues sets a component's type, parent and params from the
+// flattened values of componentValues.
+func setComponentValues(c *Component, values map[string]json.RawMessage) error {
+	var name string
+	if err := json.Unmarshal(values["component_name"], &name); err != nil {
+		return err
+	}
+	c.ParentID = ""
+	if v, ok := values["parent_id"]; ok {
+		if err := json.Unmarshal(v, &c.ParentID); err != nil {
+			return err
+		}
+	}
+
+	data, ok := values["params"]
+	if !ok {
+		members := make(map[string]json.RawMessage)
+		for key, value := range values {
+			if name, ok := strings.CutPrefix(key, "params."); ok {
+				members[name] = value
+			}
+		}
+		var err error
+		if data, err = json.Marshal(members); err != nil {
+			return err
+		}
+	}
+
+	// Params that do not fit their type are kept raw, as when decoding.
+	params := newComponentParams(name)
+	if err := json.Unmarshal(data, params); err != nil {
+		params = &RawParams{Name: name, JSON: data}
+	}
+	c.Obj.ComponentName = name
+	c.Obj.Params = params
+	return nil
+}
+
+func mergedEdgeCandidates(snapshots ...*dslSnapshot) []DSLEdge {
+	all := make(map[DSLEdge]bool)
+	for _, s := range snapshots {
+		for e := range s.edges {
+			all[e] = true
+		}
+	}
+	return sortedEdges(all)
+}
+
+// relink sets the Downstream and Upstream lists of the components to edges,
+// keeping the order of the entries that remain.
+func relink(d *DSL, edges map[DSLEdge]bool) {
+	downstream := make(map[string][]string)
+	upstream := make(map[string][]string)
+	for _, e := range sortedEdges(edges) {
+		downstream[e.From] = append(downstream[e.From], e.To)
+		upstream[e.To] = append(upstream[e.To], e.From)
+	}
+
+	for id, c := range d.Components {
+		c.Downstream = reorder(c.Downstream, downstream[id])
+		c.Upstream = reorder(c.Upstream, upstream[id])
+	}
+}
+
+// reorder returns want with the entries also in have first, in their order
+// in have.
+func reorder(have, want []string) []string {
+	ordered := make([]string, 0, len(want))
+	for _, id := range have {
+		if containsString(want, id) && !containsString(ordered, id) {
+			ordered = append(ordered, id)
+		}
+	}
+	for _, id := range want {
+		if !containsString(ordered, id) {
+			ordered = append(ordered, id)
+		}
+	}
+	return ordered
+}
+
+// mergeGraph brings the graph of merged in line with its components: nodes
+// of removed components are dropped, nodes of components added by remote are
+// taken from its graph, and the forms of nodes whose params changed are
+// updated. Nodes that are not components, such as notes, are kept.
+func mergeGraph(merged, theirs *DSL, edges map[DSLEdge]bool, changed map[string]bool, componentIDs []string) error {
+	isComponent := make(map[string]bool, len(componentIDs))
+	for _, id := range componentIDs {
+		isComponent[id] = true
+	}
+
+	var nodes []GraphNode
+	drawnNodes := make(map[string]bool)
+	for _, n := range merged.Graph.Nodes {
+		if isComponent[n.ID] && merged.Components[n.ID] == nil {
+			continue
+		}
+		if changed[n.ID] {
+			data, err := json.Marshal(merged.Components[n.ID].Obj.Params)
+			if err != nil {
+				return fmt.Errorf("error merging graph node %s: %w", n.ID, err)
+			}
+			var form map[string]interface{}
+			if err := json.Unmarshal(data, &form); err == nil {
+				n.Data.Form = form
+			}
+		}
+		nodes = append(nodes, n)
+		drawnNodes[n.ID] = true
+	}
+	for _, n := range theirs.Graph.Nodes {
+		if merged.Components[n.ID] != nil && !drawnNodes[n.ID] {
+			nodes = append(nodes, n)
+			drawnNodes[n.ID] = true
+		}
+	}
+
+	var graphEdges []GraphEdge
+	drawnEdges := make(map[DSLEdge]bool)
+	keep := func(e GraphEdge) bool {
+		link := DSLEdge{From: e.Source, To: e.Target}
+		if isComponent[e.Source] || isComponent[e.Target] {
+			return edges[link]
+		}
+		return true
+	}
+	for _, e := range merged.Graph.Edges {
+		if keep(e) {
+			graphEdges = append(graphEdges, e)
+			drawnEdges[DSLEdge{From: e.Source, To: e.Target}] = true
+		}
+	}
+	for _, e := range theirs.Graph.Edges {
+		link := DSLEdge{From: e.Source, To: e.Target}
+		if edges[link] && !drawnEdges[link] {
+			graphEdges = append(graphEdges, e)
+		}
+	}
+
+	merged.Graph.Nodes = nodes
+	merged.Graph.Edges = graphEdges
+	return nil
+}
+
+// copyDSL returns a deep copy of d, or an empty canvas if d is nil.
+func copyDSL(d *DSL) (*DSL, error) {
+	if d == nil {
+		return &DSL{Components: make(map[string]*Component)}, nil
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, fmt.Errorf("error copying DSL: %w", err)
+	}
+	var c DSL
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("error copying DSL: %w", err)
+	}
+	return &c, nil
+}
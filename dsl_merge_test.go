@@ -0,0 +1,184 @@
+package ragflow_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+	"github.com/kevinroleke/ragflow-go/ragflowtest"
+)
+
+// mergeBaseDSL has a Retrieval component whose params are kept raw because
+// top_n is saved as a string.
+const mergeBaseDSL = `{
+	"components": {
+		"begin": {"obj": {"component_name": "Begin", "params": {"prologue": "Hi!"}}, "downstream": ["Retrieval:Docs"], "upstream": []},
+		"Retrieval:Docs": {"obj": {"component_name": "Retrieval", "params": {"kb_ids": ["kb1"], "top_n": "8"}}, "downstream": ["Generate:Reply"], "upstream": ["begin"]},
+		"Generate:Reply": {"obj": {"component_name": "Generate", "params": {"llm_id": "deepseek-chat", "prompt": "Answer from: {input}"}}, "downstream": ["Answer:Talk"], "upstream": ["Retrieval:Docs"]},
+		"Answer:Talk": {"obj": {"component_name": "Answer", "params": {}}, "downstream": [], "upstream": ["Generate:Reply"]}
+	},
+	"graph": {"nodes": [], "edges": []},
+	"history": [],
+	"path": [],
+	"answer": [],
+	"messages": [],
+	"reference": []
+}`
+
+// editDSL decodes mergeBaseDSL with each old string replaced by the new
+// string following it.
+func editDSL(t *testing.T, oldnew ...string) *ragflow.DSL {
+	t.Helper()
+	var dsl ragflow.DSL
+	if err := json.Unmarshal([]byte(strings.NewReplacer(oldnew...).Replace(mergeBaseDSL)), &dsl); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	return &dsl
+}
+
+const (
+	basePrompt   = `"prompt": "Answer from: {input}"`
+	localPrompt  = `"prompt": "Answer briefly from: {input}"`
+	remotePrompt = `"prompt": "Answer in detail from: {input}"`
+	baseKBs      = `"kb_ids": ["kb1"]`
+	remoteKBs    = `"kb_ids": ["kb1", "kb2"]`
+)
+
+func TestDiffDSL(t *testing.T) {
+	old := editDSL(t)
+	new := editDSL(t, basePrompt, localPrompt, baseKBs, remoteKBs)
+	delete(new.Components, "Answer:Talk")
+	new.Components["Generate:Reply"].Downstream = []string{"Message:Bye"}
+	new.Components["Message:Bye"] = &ragflow.Component{
+		Obj:      ragflow.ComponentObject{ComponentName: "Message", Params: &ragflow.MessageParams{Messages: []string{"Bye!"}}},
+		Upstream: []string{"Generate:Reply"},
+	}
+
+	diff, err := ragflow.DiffDSL(old, new)
+	if err != nil {
+		t.Fatalf("DiffDSL: %v", err)
+	}
+	want := `+ component Message:Bye
+- component Answer:Talk
+~ Generate:Reply params.prompt: "Answer from: {input}" -> "Answer briefly from: {input}"
+~ Retrieval:Docs params.kb_ids: ["kb1"] -> ["kb1","kb2"]
++ edge Generate:Reply -> Message:Bye
+- edge Generate:Reply -> Answer:Talk
+`
+	if got := diff.String(); got != want {
+		t.Errorf("diff =\n%s\nwant\n%s", got, want)
+	}
+
+	if diff, err := ragflow.DiffDSL(old, editDSL(t)); err != nil || !diff.Empty() {
+		t.Errorf("DiffDSL of equal canvases = %v, %v", diff, err)
+	}
+}
+
+// assertSameDSL fails the test if got and want differ in anything DiffDSL
+// compares.
+func assertSameDSL(t *testing.T, got, want *ragflow.DSL) {
+	t.Helper()
+	diff, err := ragflow.DiffDSL(want, got)
+	if err != nil {
+		t.Fatalf("DiffDSL: %v", err)
+	}
+	if !diff.Empty() {
+		t.Errorf("canvas differs from the expected one:\n%s", diff)
+	}
+}
+
+func TestMergeDSL(t *testing.T) {
+	base := editDSL(t)
+	local := editDSL(t, basePrompt, localPrompt)
+	remote := editDSL(t, baseKBs, remoteKBs)
+
+	merged, conflicts, err := ragflow.MergeDSL(base, local, remote)
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("MergeDSL = %v, %v", conflicts, err)
+	}
+	assertSameDSL(t, merged, editDSL(t, basePrompt, localPrompt, baseKBs, remoteKBs))
+
+	// The remote change to the Retrieval params, which do not fit their
+	// type, is merged in without losing the string top_n.
+	p, ok := merged.Components["Retrieval:Docs"].Obj.Params.(*ragflow.RawParams)
+	if !ok {
+		t.Fatalf("Retrieval params = %#v, want *RawParams", merged.Components["Retrieval:Docs"].Obj.Params)
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal(p.JSON, &params); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if want := map[string]interface{}{"kb_ids": []interface{}{"kb1", "kb2"}, "top_n": "8"}; !reflect.DeepEqual(params, want) {
+		t.Errorf("Retrieval params = %v, want %v", params, want)
+	}
+}
+
+func TestMergeDSLConflict(t *testing.T) {
+	base := editDSL(t)
+	local := editDSL(t, basePrompt, localPrompt)
+	remote := editDSL(t, basePrompt, remotePrompt, baseKBs, remoteKBs)
+
+	merged, conflicts, err := ragflow.MergeDSL(base, local, remote)
+	if err != nil {
+		t.Fatalf("MergeDSL: %v", err)
+	}
+	want := []ragflow.DSLConflict{{
+		ComponentID: "Generate:Reply",
+		Key:         "params.prompt",
+		Base:        json.RawMessage(`"Answer from: {input}"`),
+		Local:       json.RawMessage(`"Answer briefly from: {input}"`),
+		Remote:      json.RawMessage(`"Answer in detail from: {input}"`),
+	}}
+	if !reflect.DeepEqual(conflicts, want) {
+		t.Fatalf("conflicts = %v, want %v", conflicts, want)
+	}
+	// Conflicts are resolved in favour of local; the other changes merge.
+	assertSameDSL(t, merged, editDSL(t, basePrompt, localPrompt, baseKBs, remoteKBs))
+}
+
+func TestUpdateAgentDSL(t *testing.T) {
+	srv := ragflowtest.NewServer()
+	defer srv.Close()
+	client := srv.NewClient()
+	ctx := context.Background()
+
+	created, err := client.CreateAgent(ctx, ragflow.CreateAgentRequest{Name: "helper", DSL: editDSL(t)})
+	if err != nil {
+		t.Fatalf("CreateAgent: %v", err)
+	}
+	base, err := client.GetAgent(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetAgent: %v", err)
+	}
+
+	// Someone else changes the datasets after base was fetched.
+	if _, err := client.UpdateAgent(ctx, base.ID, ragflow.UpdateAgentRequest{DSL: editDSL(t, baseKBs, remoteKBs)}); err != nil {
+		t.Fatalf("UpdateAgent: %v", err)
+	}
+
+	if _, err := client.UpdateAgentDSL(ctx, base, editDSL(t, basePrompt, localPrompt)); err != nil {
+		t.Fatalf("UpdateAgentDSL: %v", err)
+	}
+	want := editDSL(t, basePrompt, localPrompt, baseKBs, remoteKBs)
+	current, err := client.GetAgent(ctx, base.ID)
+	if err != nil {
+		t.Fatalf("GetAgent: %v", err)
+	}
+	assertSameDSL(t, current.DSL, want)
+
+	// base is now stale on the prompt too, so a different prompt conflicts
+	// and nothing is saved.
+	_, err = client.UpdateAgentDSL(ctx, base, editDSL(t, basePrompt, remotePrompt))
+	var mergeErr *ragflow.DSLMergeError
+	if !errors.Is(err, ragflow.ErrDSLConflict) || !errors.As(err, &mergeErr) || len(mergeErr.Conflicts) != 1 || mergeErr.Conflicts[0].Key != "params.prompt" {
+		t.Fatalf("UpdateAgentDSL error = %v, want a conflict on the prompt", err)
+	}
+	if current, err = client.GetAgent(ctx, base.ID); err != nil {
+		t.Fatalf("GetAgent: %v", err)
+	}
+	assertSameDSL(t, current.DSL, want)
+}
@@ -0,0 +1,331 @@
+package ragflow
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// ComponentParams are the parameters of a canvas component. Decoded DSLs
+// hold pointers to the types below; RawParams stands in for component types
+// without a Go type.
+type ComponentParams interface {
+	ComponentName() string
+}
+
+var componentParamTypes = map[string]func() ComponentParams{
+	"Begin":           func() ComponentParams { return &BeginParams{} },
+	"Answer":          func() ComponentParams { return &AnswerParams{} },
+	"Retrieval":       func() ComponentParams { return &RetrievalParams{} },
+	"Generate":        func() ComponentParams { return &GenerateParams{} },
+	"Categorize":      func() ComponentParams { return &CategorizeParams{} },
+	"Switch":          func() ComponentParams { return &SwitchParams{} },
+	"Message":         func() ComponentParams { return &MessageParams{} },
+	"RewriteQuestion": func() ComponentParams { return &RewriteQuestionParams{} },
+	"KeywordExtract":  func() ComponentParams { return &KeywordExtractParams{} },
+}
+
+func newComponentParams(name string) ComponentParams {
+	if newParams, ok := componentParamTypes[name]; ok {
+		return newParams()
+	}
+	return &RawParams{Name: name}
+}
+
+// paramsPointer returns params set by value as a pointer, the form decoding
+// produces, so that both can be handled alike.
+func paramsPointer(params ComponentParams) ComponentParams {
+	v := reflect.ValueOf(params)
+	if !v.IsValid() || v.Kind() == reflect.Pointer {
+		return params
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	return ptr.Interface().(ComponentParams)
+}
+
+// RawParams holds the parameters of a component type this package has no
+// type for, unchanged.
+type RawParams struct {
+	Name string
+	JSON json.RawMessage
+}
+
+func (p RawParams) ComponentName() string { return p.Name }
+
+func (p *RawParams) UnmarshalJSON(data []byte) error {
+	p.JSON = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+func (p RawParams) MarshalJSON() ([]byte, error) {
+	if len(p.JSON) == 0 {
+		return []byte("{}"), nil
+	}
+	return p.JSON, nil
+}
+
+// BeginParams configure the entry point of an agent.
+type BeginParams struct {
+	Prologue string       `json:"prologue,omitempty"`
+	Query    []BeginQuery `json:"query,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+// BeginQuery is an input the agent asks for before the conversation starts.
+type BeginQuery struct {
+	Key      string   `json:"key"`
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Optional bool     `json:"optional"`
+	Options  []string `json:"options,omitempty"`
+}
+
+func (p BeginParams) ComponentName() string { return "Begin" }
+
+func (p *BeginParams) UnmarshalJSON(data []byte) error {
+	type plain BeginParams
+	return decodeObject(data, (*plain)(p), &p.raw)
+}
+
+func (p BeginParams) MarshalJSON() ([]byte, error) {
+	type plain BeginParams
+	return encodeObject(plain(p), p.raw)
+}
+
+// AnswerParams configure the component that returns output to the user and
+// waits for the next message.
+type AnswerParams struct {
+	PostAnswers []string `json:"post_answers,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+func (p AnswerParams) ComponentName() string { return "Answer" }
+
+func (p *AnswerParams) UnmarshalJSON(data []byte) error {
+	type plain AnswerParams
+	return decodeObject(data, (*plain)(p), &p.raw)
+}
+
+func (p AnswerParams) MarshalJSON() ([]byte, error) {
+	type plain AnswerParams
+	return encodeObject(plain(p), p.raw)
+}
+
+type RetrievalParams struct {
+	DatasetIDs               []string `json:"kb_ids,omitempty"`
+	SimilarityThreshold      float64  `json:"similarity_threshold,omitempty"`
+	KeywordsSimilarityWeight float64  `json:"keywords_similarity_weight,omitempty"`
+	TopN                     int      `json:"top_n,omitempty"`
+	TopK                     int      `json:"top_k,omitempty"`
+	RerankID                 string   `json:"rerank_id,omitempty"`
+	EmptyResponse            string   `json:"empty_response,omitempty"`
+	UseKG                    bool     `json:"use_kg,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+func (p RetrievalParams) ComponentName() string { return "Retrieval" }
+
+func (p *RetrievalParams) UnmarshalJSON(data []byte) error {
+	type plain RetrievalParams
+	return decodeObject(data, (*plain)(p), &p.raw)
+}
+
+func (p RetrievalParams) MarshalJSON() ([]byte, error) {
+	type plain RetrievalParams
+	return encodeObject(plain(p), p.raw)
+}
+
+type GenerateParams struct {
+	LLMID                    string  `json:"llm_id,omitempty"`
+	Prompt                   string  `json:"prompt,omitempty"`
+	Temperature              float64 `json:"temperature,omitempty"`
+	TopP                     float64 `json:"top_p,omitempty"`
+	PresencePenalty          float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty         float64 `json:"frequency_penalty,omitempty"`
+	MaxTokens                int     `json:"max_tokens,omitempty"`
+	MessageHistoryWindowSize int     `json:"message_history_window_size,omitempty"`
+	// Cite adds citations of the retrieved chunks to the answer. RAGFlow
+	// cites by default.
+	Cite *bool `json:"cite,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+func (p GenerateParams) ComponentName() string { return "Generate" }
+
+func (p *GenerateParams) UnmarshalJSON(data []byte) error {
+	type plain GenerateParams
+	return decodeObject(data, (*plain)(p), &p.raw)
+}
+
+func (p GenerateParams) MarshalJSON() ([]byte, error) {
+	type plain GenerateParams
+	return encodeObject(plain(p), p.raw)
+}
+
+// CategorizeParams route the conversation to the component of the category
+// the LLM picks for the input.
+type CategorizeParams struct {
+	LLMID                    string              `json:"llm_id,omitempty"`
+	Categories               map[string]Category `json:"category_description,omitempty"`
+	MessageHistoryWindowSize int                 `json:"message_history_window_size,omitempty"`
+	Temperature              float64             `json:"temperature,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+type Category struct {
+	Description string `json:"description,omitempty"`
+	// Examples are sample inputs of the category. RAGFlow saves them either
+	// as a list or as one newline-separated string; decoded categories are
+	// encoded back in the form they came in.
+	Examples []string `json:"examples,omitempty"`
+	// To is the ID of the component the category leads to.
+	To string `json:"to"`
+
+	examplesText bool
+}
+
+func (c *Category) UnmarshalJSON(data []byte) error {
+	type plain Category
+	var v struct {
+		plain
+		Examples json.RawMessage `json:"examples"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*c = Category(v.plain)
+
+	var text string
+	if err := json.Unmarshal(v.Examples, &text); err == nil {
+		c.examplesText = true
+		if text != "" {
+			c.Examples = strings.Split(text, "\n")
+		}
+		return nil
+	}
+	if len(v.Examples) == 0 || string(v.Examples) == "null" {
+		return nil
+	}
+	return json.Unmarshal(v.Examples, &c.Examples)
+}
+
+func (c Category) MarshalJSON() ([]byte, error) {
+	type plain Category
+	if !c.examplesText {
+		return json.Marshal(plain(c))
+	}
+	return json.Marshal(struct {
+		plain
+		Examples string `json:"examples"`
+	}{plain(c), strings.Join(c.Examples, "\n")})
+}
+
+func (p CategorizeParams) ComponentName() string { return "Categorize" }
+
+func (p *CategorizeParams) UnmarshalJSON(data []byte) error {
+	type plain CategorizeParams
+	return decodeObject(data, (*plain)(p), &p.raw)
+}
+
+func (p CategorizeParams) MarshalJSON() ([]byte, error) {
+	type plain CategorizeParams
+	return encodeObject(plain(p), p.raw)
+}
+
+// SwitchParams route to the component of the first condition that holds, or
+// to End if none does.
+type SwitchParams struct {
+	Conditions []SwitchCondition `json:"conditions,omitempty"`
+	End        string            `json:"end_cpn_id,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+type SwitchCondition struct {
+	LogicalOperator string       `json:"logical_operator"`
+	Items           []SwitchItem `json:"items"`
+	To              string       `json:"to"`
+}
+
+// SwitchItem compares the output of a component with Value.
+type SwitchItem struct {
+	ComponentID string `json:"cpn_id"`
+	Operator    string `json:"operator"`
+	Value       string `json:"value"`
+}
+
+func (p SwitchParams) ComponentName() string { return "Switch" }
+
+func (p *SwitchParams) UnmarshalJSON(data []byte) error {
+	type plain SwitchParams
+	return decodeObject(data, (*plain)(p), &p.raw)
+}
+
+func (p SwitchParams) MarshalJSON() ([]byte, error) {
+	type plain SwitchParams
+	return encodeObject(plain(p), p.raw)
+}
+
+// MessageParams reply with one of Messages, picked at random.
+type MessageParams struct {
+	Messages []string `json:"messages,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+func (p MessageParams) ComponentName() string { return "Message" }
+
+func (p *MessageParams) UnmarshalJSON(data []byte) error {
+	type plain MessageParams
+	return decodeObject(data, (*plain)(p), &p.raw)
+}
+
+func (p MessageParams) MarshalJSON() ([]byte, error) {
+	type plain MessageParams
+	return encodeObject(plain(p), p.raw)
+}
+
+type RewriteQuestionParams struct {
+	LLMID                    string `json:"llm_id,omitempty"`
+	Language                 string `json:"language,omitempty"`
+	MessageHistoryWindowSize int    `json:"message_history_window_size,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+func (p RewriteQuestionParams) ComponentName() string { return "RewriteQuestion" }
+
+func (p *RewriteQuestionParams) UnmarshalJSON(data []byte) error {
+	type plain RewriteQuestionParams
+	return decodeObject(data, (*plain)(p), &p.raw)
+}
+
+func (p RewriteQuestionParams) MarshalJSON() ([]byte, error) {
+	type plain RewriteQuestionParams
+	return encodeObject(plain(p), p.raw)
+}
+
+type KeywordExtractParams struct {
+	LLMID string `json:"llm_id,omitempty"`
+	TopN  int    `json:"top_n,omitempty"`
+
+	raw map[string]json.RawMessage
+}
+
+func (p KeywordExtractParams) ComponentName() string { return "KeywordExtract" }
+
+func (p *KeywordExtractParams) UnmarshalJSON(data []byte) error {
+	type plain KeywordExtractParams
+	return decodeObject(data, (*plain)(p), &p.raw)
+}
+
+func (p KeywordExtractParams) MarshalJSON() ([]byte, error) {
+	type plain KeywordExtractParams
+	return encodeObject(plain(p), p.raw)
+}
@@ -0,0 +1,275 @@
+package ragflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReadDSLFile reads a canvas from a JSON file holding either the DSL itself
+// or an agent as returned by GetAgent.
+func ReadDSLFile(path string) (*DSL, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading DSL: %w", err)
+	}
+
+	var probe struct {
+		Components json.RawMessage `json:"components"`
+		DSL        json.RawMessage `json:"dsl"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("error parsing DSL: %w", err)
+	}
+	if probe.Components == nil && probe.DSL != nil {
+		data = probe.DSL
+	}
+
+	var dsl DSL
+	if err := json.Unmarshal(data, &dsl); err != nil {
+		return nil, fmt.Errorf("error parsing DSL: %w", err)
+	}
+	return &dsl, nil
+}
+
+// Mermaid renders the canvas as a Mermaid flowchart. Nodes are labelled with
+// the component ID, its type and key params, edges out of Categorize and
+// Switch components with the branch they belong to.
+func (d *DSL) Mermaid() string {
+	g := newDSLDiagram(d)
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, n := range g.nodes {
+		label := mermaidEscape(strings.Join(n.lines, "\n"))
+		label = strings.ReplaceAll(label, "\n", "<br/>")
+		left, right := "[\"", "\"]"
+		switch n.name {
+		case "Begin":
+			left, right = "([\"", "\"])"
+		case "Answer":
+			left, right = "[[\"", "\"]]"
+		case "Categorize", "Switch":
+			left, right = "{\"", "\"}"
+		}
+		fmt.Fprintf(&b, "    n%d%s%s%s\n", i, left, label, right)
+	}
+	for _, e := range g.edges {
+		if e.label == "" {
+			fmt.Fprintf(&b, "    n%d --> n%d\n", e.from, e.to)
+		} else {
+			fmt.Fprintf(&b, "    n%d -->|\"%s\"| n%d\n", e.from, mermaidEscape(e.label), e.to)
+		}
+	}
+	return b.String()
+}
+
+// DOT renders the canvas as a Graphviz digraph, labelled like Mermaid.
+func (d *DSL) DOT() string {
+	g := newDSLDiagram(d)
+
+	var b strings.Builder
+	b.WriteString("digraph agent {\n    rankdir=LR;\n    node [shape=box];\n")
+	for _, n := range g.nodes {
+		shape := ""
+		switch n.name {
+		case "Begin", "Answer":
+			shape = ", shape=oval"
+		case "Categorize", "Switch":
+			shape = ", shape=diamond"
+		}
+		fmt.Fprintf(&b, "    %s [label=%s%s];\n", dotQuote(n.id), dotQuote(strings.Join(n.lines, "\n")), shape)
+	}
+	for _, e := range g.edges {
+		from, to := dotQuote(g.nodes[e.from].id), dotQuote(g.nodes[e.to].id)
+		if e.label == "" {
+			fmt.Fprintf(&b, "    %s -> %s;\n", from, to)
+		} else {
+			fmt.Fprintf(&b, "    %s -> %s [label=%s];\n", from, to, dotQuote(e.label))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+type dslDiagram struct {
+	nodes []diagramNode
+	edges []diagramEdge
+}
+
+type diagramNode struct {
+	id    string
+	name  string
+	lines []string
+}
+
+type diagramEdge struct {
+	from, to int
+	label    string
+}
+
+// newDSLDiagram lists the components, Begin first and the others by ID, and
+// the edges between them. Edges to missing components are left out.
+func newDSLDiagram(d *DSL) *dslDiagram {
+	g := &dslDiagram{}
+	if d == nil {
+		return g
+	}
+
+	ids := make([]string, 0, len(d.Components))
+	for id, c := range d.Components {
+		if c != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		bi, bj := d.Components[ids[i]].Obj.name() == "Begin", d.Components[ids[j]].Obj.name() == "Begin"
+		if bi != bj {
+			return bi
+		}
+		return ids[i] < ids[j]
+	})
+
+	index := make(map[string]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+		obj := d.Components[id].Obj
+		name := obj.name()
+		lines := []string{id}
+		if name != "" && !strings.HasPrefix(id, name+":") {
+			lines = append(lines, name)
+		}
+		g.nodes = append(g.nodes, diagramNode{
+			id:    id,
+			name:  name,
+			lines: append(lines, paramSummary(obj.Params)...),
+		})
+	}
+
+	for _, id := range ids {
+		c := d.Components[id]
+		labels := branchLabels(c.Obj.Params)
+
+		var targets []string
+		for _, down := range c.Downstream {
+			if !containsString(targets, down) {
+				targets = append(targets, down)
+			}
+		}
+		for _, e := range impliedEdges(id, c.Obj.Params) {
+			if !containsString(targets, e.to) {
+				targets = append(targets, e.to)
+			}
+		}
+
+		for _, to := range targets {
+			if j, ok := index[to]; ok {
+				g.edges = append(g.edges, diagramEdge{from: index[id], to: j, label: strings.Join(labels[to], " / ")})
+			}
+		}
+	}
+	return g
+}
+
+// paramSummary describes the params that matter most when reviewing a
+// canvas, one per line.
+func paramSummary(params ComponentParams) []string {
+	var lines []string
+	add := func(key, value string) {
+		if value != "" {
+			lines = append(lines, key+": "+value)
+		}
+	}
+
+	switch p := paramsPointer(params).(type) {
+	case *BeginParams:
+		add("prologue", truncate(p.Prologue, 40))
+		for _, q := range p.Query {
+			add("input", q.Key)
+		}
+	case *RetrievalParams:
+		add("datasets", strings.Join(p.DatasetIDs, ", "))
+		if p.TopN > 0 {
+			add("top_n", strconv.Itoa(p.TopN))
+		}
+		if p.SimilarityThreshold > 0 {
+			add("similarity", strconv.FormatFloat(p.SimilarityThreshold, 'g', -1, 64))
+		}
+		add("rerank", p.RerankID)
+	case *GenerateParams:
+		add("llm", p.LLMID)
+		add("prompt", truncate(p.Prompt, 40))
+	case *CategorizeParams:
+		add("llm", p.LLMID)
+	case *RewriteQuestionParams:
+		add("llm", p.LLMID)
+	case *KeywordExtractParams:
+		add("llm", p.LLMID)
+	case *MessageParams:
+		if len(p.Messages) > 0 {
+			add("message", truncate(p.Messages[0], 40))
+		}
+	}
+	return lines
+}
+
+// branchLabels returns the labels of a component's outgoing edges by target.
+func branchLabels(params ComponentParams) map[string][]string {
+	labels := make(map[string][]string)
+	switch p := paramsPointer(params).(type) {
+	case *CategorizeParams:
+		names := make([]string, 0, len(p.Categories))
+		for name := range p.Categories {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if to := p.Categories[name].To; to != "" {
+				labels[to] = append(labels[to], name)
+			}
+		}
+	case *SwitchParams:
+		for _, cond := range p.Conditions {
+			if cond.To == "" {
+				continue
+			}
+			items := make([]string, 0, len(cond.Items))
+			for _, item := range cond.Items {
+				items = append(items, fmt.Sprintf("%s %s %s", item.ComponentID, item.Operator, strconv.Quote(item.Value)))
+			}
+			op := " " + cond.LogicalOperator + " "
+			if cond.LogicalOperator == "" {
+				op = " and "
+			}
+			labels[cond.To] = append(labels[cond.To], strings.Join(items, op))
+		}
+		if p.End != "" {
+			labels[p.End] = append(labels[p.End], "else")
+		}
+	}
+	return labels
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+var mermaidReplacer = strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;")
+
+func mermaidEscape(s string) string {
+	return mermaidReplacer.Replace(s)
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,111 @@
+package ragflow_test
+
+import (
+	"testing"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+)
+
+// renderedDSL builds a canvas with every node shape and kind of edge label.
+func renderedDSL(t *testing.T) *ragflow.DSL {
+	t.Helper()
+	dsl, err := ragflow.NewDSLBuilder().
+		Component("begin", ragflow.BeginParams{Prologue: `Hi! Ask me "anything"`, Query: []ragflow.BeginQuery{{Key: "language"}}}).
+		Component("Answer:Talk", ragflow.AnswerParams{}).
+		Component("Categorize:Route", ragflow.CategorizeParams{
+			LLMID: "deepseek-chat",
+			Categories: map[string]ragflow.Category{
+				"product":   {Description: "Questions about the product", To: "Retrieval:Docs"},
+				"smalltalk": {Description: "Greetings", To: "Message:Hello"},
+				"thanks":    {Description: "Thanks", To: "Message:Hello"},
+			},
+		}).
+		Component("Message:Hello", ragflow.MessageParams{Messages: []string{"Hello <there>!"}}).
+		Component("Retrieval:Docs", ragflow.RetrievalParams{DatasetIDs: []string{"kb1", "kb2"}, TopN: 8, SimilarityThreshold: 0.2}).
+		Component("Switch:Found", ragflow.SwitchParams{
+			Conditions: []ragflow.SwitchCondition{{
+				LogicalOperator: "or",
+				Items: []ragflow.SwitchItem{
+					{ComponentID: "Retrieval:Docs", Operator: "empty"},
+					{ComponentID: "Retrieval:Docs", Operator: "=", Value: "none"},
+				},
+				To: "Message:Hello",
+			}},
+			End: "Generate:Reply",
+		}).
+		Component("Generate:Reply", ragflow.GenerateParams{LLMID: "deepseek-chat", Prompt: "Answer from: {input}"}).
+		Chain("begin", "Answer:Talk", "Categorize:Route").
+		Chain("Retrieval:Docs", "Switch:Found").
+		Edge("Generate:Reply", "Answer:Talk").
+		Edge("Message:Hello", "Answer:Talk").
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	return dsl
+}
+
+const wantMermaid = `flowchart LR
+    n0(["begin<br/>Begin<br/>prologue: Hi! Ask me #quot;anything#quot;<br/>input: language"])
+    n1[["Answer:Talk"]]
+    n2{"Categorize:Route<br/>llm: deepseek-chat"}
+    n3["Generate:Reply<br/>llm: deepseek-chat<br/>prompt: Answer from: {input}"]
+    n4["Message:Hello<br/>message: Hello #lt;there#gt;!"]
+    n5["Retrieval:Docs<br/>datasets: kb1, kb2<br/>top_n: 8<br/>similarity: 0.2"]
+    n6{"Switch:Found"}
+    n0 --> n1
+    n1 --> n2
+    n2 -->|"product"| n5
+    n2 -->|"smalltalk / thanks"| n4
+    n3 --> n1
+    n4 --> n1
+    n5 --> n6
+    n6 -->|"Retrieval:Docs empty #quot;#quot; or Retrieval:Docs = #quot;none#quot;"| n4
+    n6 -->|"else"| n3
+`
+
+const wantDOT = `digraph agent {
+    rankdir=LR;
+    node [shape=box];
+    "begin" [label="begin\nBegin\nprologue: Hi! Ask me \"anything\"\ninput: language", shape=oval];
+    "Answer:Talk" [label="Answer:Talk", shape=oval];
+    "Categorize:Route" [label="Categorize:Route\nllm: deepseek-chat", shape=diamond];
+    "Generate:Reply" [label="Generate:Reply\nllm: deepseek-chat\nprompt: Answer from: {input}"];
+    "Message:Hello" [label="Message:Hello\nmessage: Hello <there>!"];
+    "Retrieval:Docs" [label="Retrieval:Docs\ndatasets: kb1, kb2\ntop_n: 8\nsimilarity: 0.2"];
+    "Switch:Found" [label="Switch:Found", shape=diamond];
+    "begin" -> "Answer:Talk";
+    "Answer:Talk" -> "Categorize:Route";
+    "Categorize:Route" -> "Retrieval:Docs" [label="product"];
+    "Categorize:Route" -> "Message:Hello" [label="smalltalk / thanks"];
+    "Generate:Reply" -> "Answer:Talk";
+    "Message:Hello" -> "Answer:Talk";
+    "Retrieval:Docs" -> "Switch:Found";
+    "Switch:Found" -> "Message:Hello" [label="Retrieval:Docs empty \"\" or Retrieval:Docs = \"none\""];
+    "Switch:Found" -> "Generate:Reply" [label="else"];
+}
+`
+
+func TestDSLMermaid(t *testing.T) {
+	if got := renderedDSL(t).Mermaid(); got != wantMermaid {
+		t.Errorf("Mermaid() =\n%s\nwant\n%s", got, wantMermaid)
+	}
+}
+
+func TestDSLDOT(t *testing.T) {
+	if got := renderedDSL(t).DOT(); got != wantDOT {
+		t.Errorf("DOT() =\n%s\nwant\n%s", got, wantDOT)
+	}
+}
+
+func TestBuildGraphHandles(t *testing.T) {
+	dsl := renderedDSL(t)
+	if len(dsl.Graph.Nodes) != len(dsl.Components) {
+		t.Fatalf("got %d graph nodes for %d components", len(dsl.Graph.Nodes), len(dsl.Components))
+	}
+	for _, n := range dsl.Graph.Nodes {
+		if n.SourcePosition != "right" || n.TargetPosition != "left" {
+			t.Errorf("node %s: edges leave on the %s and enter on the %s, want right and left", n.ID, n.SourcePosition, n.TargetPosition)
+		}
+	}
+}
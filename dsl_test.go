@@ -0,0 +1,120 @@
+package ragflow_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+)
+
+// mismatchedDSL has parameters that do not fit their Go types, category
+// examples saved both as a list and as a string, and a history entry whose
+// content is not a string, as older RAGFlow versions save them.
+const mismatchedDSL = `{
+	"components": {
+		"begin": {"obj": {"component_name": "Begin", "params": {"prologue": "Hi!"}}, "downstream": ["Retrieval:Docs"], "upstream": []},
+		"Retrieval:Docs": {"obj": {"component_name": "Retrieval", "params": {"kb_ids": ["kb1"], "top_n": "8"}}, "downstream": ["Categorize:Intent"], "upstream": ["begin"]},
+		"Categorize:Intent": {"obj": {"component_name": "Categorize", "params": {"category_description": {"greeting": {"examples": ["hi", "hello"], "to": "Answer:Reply"}, "thanks": {"examples": "thanks\nthank you", "to": "Answer:Reply"}}}}, "downstream": ["Answer:Reply"], "upstream": ["Retrieval:Docs"]},
+		"Answer:Reply": {"obj": {"component_name": "Answer", "params": {}}, "downstream": [], "upstream": ["Categorize:Intent"]}
+	},
+	"graph": {"nodes": [], "edges": []},
+	"history": [["user", "hi"], ["assistant", {"content": "hello", "reference": []}]],
+	"path": [],
+	"answer": [],
+	"messages": [],
+	"reference": []
+}`
+
+func TestListAgentsWithMismatchedParams(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"code":0,"data":{"total":1,"items":[{"id":"a1","title":"helper","dsl":`+mismatchedDSL+`}]}}`)
+	}))
+	defer srv.Close()
+
+	client := ragflow.NewClient("key", ragflow.WithBaseURL(srv.URL))
+	agents, err := client.ListAgents(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListAgents: %v", err)
+	}
+	dsl := agents.Data.Items[0].DSL
+
+	if p, ok := dsl.Components["begin"].Obj.Params.(*ragflow.BeginParams); !ok || p.Prologue != "Hi!" {
+		t.Errorf("begin params = %#v, want *BeginParams", dsl.Components["begin"].Obj.Params)
+	}
+	if p, ok := dsl.Components["Retrieval:Docs"].Obj.Params.(*ragflow.RawParams); !ok || p.Name != "Retrieval" {
+		t.Errorf("Retrieval params = %#v, want *RawParams", dsl.Components["Retrieval:Docs"].Obj.Params)
+	}
+	p, ok := dsl.Components["Categorize:Intent"].Obj.Params.(*ragflow.CategorizeParams)
+	if !ok {
+		t.Fatalf("Categorize params = %#v, want *CategorizeParams", dsl.Components["Categorize:Intent"].Obj.Params)
+	}
+	for name, want := range map[string][]string{"greeting": {"hi", "hello"}, "thanks": {"thanks", "thank you"}} {
+		if c := p.Categories[name]; !reflect.DeepEqual(c.Examples, want) || c.To != "Answer:Reply" {
+			t.Errorf("category %s = %+v, want examples %q", name, c, want)
+		}
+	}
+
+	if len(dsl.History) != 2 || dsl.History[1].Role != "assistant" || dsl.History[0].Content != "hi" {
+		t.Fatalf("History = %+v", dsl.History)
+	}
+
+	data, err := json.Marshal(dsl)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got, want interface{}
+	json.Unmarshal(data, &got)
+	json.Unmarshal([]byte(mismatchedDSL), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DSL did not round-trip:\n got %s", data)
+	}
+}
+
+func TestValidateDSLBeginID(t *testing.T) {
+	build := func(beginID string) *ragflow.DSL {
+		t.Helper()
+		dsl, err := ragflow.NewDSLBuilder().
+			Component(beginID, ragflow.BeginParams{}).
+			Component("Answer:Talk", ragflow.AnswerParams{}).
+			Component("Generate:Reply", ragflow.GenerateParams{LLMID: "deepseek-chat"}).
+			Chain(beginID, "Answer:Talk", "Generate:Reply", "Answer:Talk").
+			Build()
+		if err != nil {
+			t.Fatalf("Build: %v", err)
+		}
+		return dsl
+	}
+	codes := func(dsl *ragflow.DSL) map[ragflow.DiagnosticCode]string {
+		t.Helper()
+		diags, err := ragflow.ValidateDSL(context.Background(), dsl, nil)
+		if err != nil {
+			t.Fatalf("ValidateDSL: %v", err)
+		}
+		found := make(map[ragflow.DiagnosticCode]string)
+		for _, d := range diags {
+			if d.Severity == ragflow.SeverityError {
+				found[d.Code] = d.ComponentID
+			}
+		}
+		return found
+	}
+
+	if found := codes(build("begin")); len(found) != 0 {
+		t.Errorf("canvas starting at begin: errors %v", found)
+	}
+	if found := codes(build("Begin:Start")); found[ragflow.DiagMissingBegin] != "Begin:Start" {
+		t.Errorf("Begin with another ID: errors %v, want %s on Begin:Start", found, ragflow.DiagMissingBegin)
+	}
+
+	dsl := build("Begin:Start")
+	dsl.Components["begin"] = dsl.Components["Answer:Talk"]
+	if found := codes(dsl); found[ragflow.DiagMissingBegin] != "begin" {
+		t.Errorf("begin is an Answer: errors %v, want %s on begin", found, ragflow.DiagMissingBegin)
+	}
+}
@@ -0,0 +1,494 @@
+package ragflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidDSL = errors.New("ragflow: invalid agent DSL")
+
+type DiagnosticSeverity string
+
+const (
+	SeverityError   DiagnosticSeverity = "error"
+	SeverityWarning DiagnosticSeverity = "warning"
+)
+
+type DiagnosticCode string
+
+const (
+	DiagMissingBegin     DiagnosticCode = "missing_begin"
+	DiagMultipleBegin    DiagnosticCode = "multiple_begin"
+	DiagMissingAnswer    DiagnosticCode = "missing_answer"
+	DiagDanglingEdge     DiagnosticCode = "dangling_edge"
+	DiagInconsistentEdge DiagnosticCode = "inconsistent_edge"
+	DiagUnreachable      DiagnosticCode = "unreachable"
+	DiagCycleWithoutExit DiagnosticCode = "cycle_without_exit"
+	DiagMissingDataset   DiagnosticCode = "missing_dataset"
+	DiagUnknownDataset   DiagnosticCode = "unknown_dataset"
+	DiagMissingLLM       DiagnosticCode = "missing_llm"
+	DiagUnknownLLM       DiagnosticCode = "unknown_llm"
+)
+
+// DSLDiagnostic is a problem ValidateDSL found in a canvas. ComponentID is
+// empty for problems of the canvas as a whole.
+type DSLDiagnostic struct {
+	Severity    DiagnosticSeverity
+	Code        DiagnosticCode
+	ComponentID string
+	Message     string
+}
+
+func (d DSLDiagnostic) String() string {
+	if d.ComponentID == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Severity, d.ComponentID, d.Message)
+}
+
+type DSLDiagnostics []DSLDiagnostic
+
+// HasErrors reports whether any diagnostic has SeverityError.
+func (ds DSLDiagnostics) HasErrors() bool {
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns a *DSLValidationError if any diagnostic has SeverityError, and
+// nil otherwise.
+func (ds DSLDiagnostics) Err() error {
+	if !ds.HasErrors() {
+		return nil
+	}
+	return &DSLValidationError{Diagnostics: ds}
+}
+
+// DSLValidationError reports a canvas that failed validation. It matches
+// ErrInvalidDSL with errors.Is.
+type DSLValidationError struct {
+	Diagnostics DSLDiagnostics
+}
+
+func (e *DSLValidationError) Error() string {
+	var lines []string
+	for _, d := range e.Diagnostics {
+		if d.Severity != SeverityError {
+			continue
+		}
+		if d.ComponentID == "" {
+			lines = append(lines, d.Message)
+		} else {
+			lines = append(lines, d.ComponentID+": "+d.Message)
+		}
+	}
+	return "invalid agent DSL: " + strings.Join(lines, "; ")
+}
+
+func (e *DSLValidationError) Is(target error) bool {
+	return target == ErrInvalidDSL
+}
+
+type ValidateDSLOptions struct {
+	// Client is used to verify the referenced datasets and LLMs against the
+	// server when VerifyDatasets or VerifyLLMs is set.
+	Client *Client
+	// VerifyDatasets checks the dataset IDs of Retrieval components against
+	// the datasets ListDatasets returns.
+	VerifyDatasets bool
+	// VerifyLLMs checks the LLM IDs of components against the models
+	// GetMyLLMs returns. It requires web UI credentials.
+	VerifyLLMs bool
+}
+
+// ValidateDSL checks a canvas for problems that RAGFlow would only report
+// when running the agent: edges to missing components, components that
+// cannot be reached from Begin, loops the agent cannot leave, and missing or
+// unknown datasets and LLMs. The error is non-nil only if the server could
+// not be queried; use the Err method of the diagnostics to fail on them:
+//
+//	diags, err := ragflow.ValidateDSL(ctx, dsl, nil)
+//	if err == nil {
+//		err = diags.Err()
+//	}
+func ValidateDSL(ctx context.Context, dsl *DSL, opts *ValidateDSLOptions) (DSLDiagnostics, error) {
+	var o ValidateDSLOptions
+	if opts != nil {
+		o = *opts
+	}
+	if (o.VerifyDatasets || o.VerifyLLMs) && o.Client == nil {
+		return nil, errors.New("ValidateDSL: verifying datasets or LLMs requires a client")
+	}
+
+	v := &dslValidator{dsl: dsl}
+	if dsl == nil || len(dsl.Components) == 0 {
+		v.report(SeverityError, DiagMissingBegin, "", "canvas has no components")
+		return v.diags, nil
+	}
+
+	v.ids = make([]string, 0, len(dsl.Components))
+	for id, c := range dsl.Components {
+		if c != nil {
+			v.ids = append(v.ids, id)
+		}
+	}
+	sort.Strings(v.ids)
+
+	v.checkEntryAndExit()
+	v.checkEdges()
+	v.checkReachability()
+	v.checkCycles()
+	v.checkReferences()
+
+	if o.VerifyDatasets {
+		if err := v.verifyDatasets(ctx, o.Client); err != nil {
+			return nil, err
+		}
+	}
+	if o.VerifyLLMs {
+		if err := v.verifyLLMs(ctx, o.Client); err != nil {
+			return nil, err
+		}
+	}
+	return v.diags, nil
+}
+
+type dslValidator struct {
+	dsl   *DSL
+	ids   []string
+	diags DSLDiagnostics
+}
+
+func (v *dslValidator) report(severity DiagnosticSeverity, code DiagnosticCode, componentID, format string, args ...interface{}) {
+	v.diags = append(v.diags, DSLDiagnostic{
+		Severity:    severity,
+		Code:        code,
+		ComponentID: componentID,
+		Message:     fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *dslValidator) componentName(id string) string {
+	c := v.dsl.Components[id]
+	if c == nil {
+		return ""
+	}
+	return c.Obj.name()
+}
+
+func (v *dslValidator) componentsNamed(name string) []string {
+	var ids []string
+	for _, id := range v.ids {
+		if v.componentName(id) == name {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// checkEntryAndExit also requires the Begin component to have the ID
+// "begin", the component RAGFlow starts every run at.
+func (v *dslValidator) checkEntryAndExit() {
+	begins := v.componentsNamed("Begin")
+	switch {
+	case len(begins) == 0:
+		v.report(SeverityError, DiagMissingBegin, "", "canvas has no Begin component")
+	case v.dsl.Components["begin"] == nil:
+		v.report(SeverityError, DiagMissingBegin, begins[0], "Begin component must have the ID begin")
+	}
+	if v.dsl.Components["begin"] != nil && v.componentName("begin") != "Begin" {
+		v.report(SeverityError, DiagMissingBegin, "begin", "component begin is a %s, not the Begin component", v.componentName("begin"))
+	}
+
+	first := "begin"
+	if len(begins) > 0 && !containsString(begins, first) {
+		first = begins[0]
+	}
+	for _, id := range begins {
+		if id != first {
+			v.report(SeverityError, DiagMultipleBegin, id, "canvas has more than one Begin component, first is %s", first)
+		}
+	}
+	if len(v.componentsNamed("Answer")) == 0 {
+		v.report(SeverityError, DiagMissingAnswer, "", "canvas has no Answer component")
+	}
+}
+
+func (v *dslValidator) checkEdges() {
+	for _, id := range v.ids {
+		c := v.dsl.Components[id]
+		for _, down := range c.Downstream {
+			target := v.dsl.Components[down]
+			if target == nil {
+				v.report(SeverityError, DiagDanglingEdge, id, "downstream component %s does not exist", down)
+			} else if !containsString(target.Upstream, id) {
+				v.report(SeverityWarning, DiagInconsistentEdge, id, "downstream component %s does not list it upstream", down)
+			}
+		}
+		for _, up := range c.Upstream {
+			source := v.dsl.Components[up]
+			if source == nil {
+				v.report(SeverityError, DiagDanglingEdge, id, "upstream component %s does not exist", up)
+			} else if !containsString(source.Downstream, id) {
+				v.report(SeverityWarning, DiagInconsistentEdge, id, "upstream component %s does not list it downstream", up)
+			}
+		}
+		for _, e := range impliedEdges(id, c.Obj.Params) {
+			if v.dsl.Components[e.to] == nil {
+				v.report(SeverityError, DiagDanglingEdge, id, "%s target %s does not exist", e.handle, e.to)
+			} else if !containsString(c.Downstream, e.to) {
+				v.report(SeverityWarning, DiagInconsistentEdge, id, "%s target %s is not downstream", e.handle, e.to)
+			}
+		}
+	}
+
+	for _, e := range v.dsl.Graph.Edges {
+		for _, end := range []string{e.Source, e.Target} {
+			if v.dsl.Components[end] == nil {
+				v.report(SeverityError, DiagDanglingEdge, end, "graph edge %s connects a component that does not exist", e.ID)
+			}
+		}
+	}
+}
+
+// successors returns the existing components a component passes control to,
+// including the targets of Categorize and Switch params.
+func (v *dslValidator) successors(id string) []string {
+	c := v.dsl.Components[id]
+	var next []string
+	add := func(to string) {
+		if v.dsl.Components[to] != nil && !containsString(next, to) {
+			next = append(next, to)
+		}
+	}
+	for _, down := range c.Downstream {
+		add(down)
+	}
+	for _, e := range impliedEdges(id, c.Obj.Params) {
+		add(e.to)
+	}
+	return next
+}
+
+func (v *dslValidator) checkReachability() {
+	queue := v.componentsNamed("Begin")
+	if len(queue) == 0 {
+		return
+	}
+
+	reached := make(map[string]bool)
+	for _, id := range queue {
+		reached[id] = true
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range v.successors(id) {
+			if !reached[next] {
+				reached[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	for _, id := range v.ids {
+		if !reached[id] {
+			v.report(SeverityWarning, DiagUnreachable, id, "component cannot be reached from Begin")
+		}
+	}
+}
+
+// checkCycles reports loops that contain no Answer component, where the agent
+// would wait for the user, and have no edge leaving them.
+func (v *dslValidator) checkCycles() {
+	for _, scc := range v.stronglyConnected() {
+		members := make(map[string]bool, len(scc))
+		for _, id := range scc {
+			members[id] = true
+		}
+		if len(scc) == 1 && !containsString(v.successors(scc[0]), scc[0]) {
+			continue
+		}
+
+		exits := false
+		for _, id := range scc {
+			if v.componentName(id) == "Answer" {
+				exits = true
+				break
+			}
+			for _, next := range v.successors(id) {
+				if !members[next] {
+					exits = true
+				}
+			}
+		}
+		if !exits {
+			sort.Strings(scc)
+			v.report(SeverityError, DiagCycleWithoutExit, scc[0], "loop through %s has no Answer component and no way out", strings.Join(scc, ", "))
+		}
+	}
+}
+
+// stronglyConnected returns the strongly connected components of the canvas,
+// using Tarjan's algorithm.
+func (v *dslValidator) stronglyConnected() [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var sccs [][]string
+
+	var visit func(id string)
+	visit = func(id string) {
+		index[id] = len(index)
+		low[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, next := range v.successors(id) {
+			if _, seen := index[next]; !seen {
+				visit(next)
+				low[id] = min(low[id], low[next])
+			} else if onStack[next] {
+				low[id] = min(low[id], index[next])
+			}
+		}
+
+		if low[id] == index[id] {
+			var scc []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				scc = append(scc, top)
+				if top == id {
+					break
+				}
+			}
+			sccs = append(sccs, scc)
+		}
+	}
+
+	for _, id := range v.ids {
+		if _, seen := index[id]; !seen {
+			visit(id)
+		}
+	}
+	return sccs
+}
+
+func (v *dslValidator) checkReferences() {
+	for _, id := range v.ids {
+		params := v.dsl.Components[id].Obj.Params
+		if p, ok := paramsPointer(params).(*RetrievalParams); ok && len(p.DatasetIDs) == 0 {
+			v.report(SeverityWarning, DiagMissingDataset, id, "Retrieval component has no datasets")
+		}
+
+		if llmID, ok := componentLLM(params); ok && llmID == "" {
+			v.report(SeverityError, DiagMissingLLM, id, "%s component has no LLM", v.componentName(id))
+		}
+	}
+}
+
+func (v *dslValidator) verifyDatasets(ctx context.Context, client *Client) error {
+	known := make(map[string]bool)
+	p := client.DatasetsPager(nil, nil)
+	for p.Next(ctx) {
+		known[p.Item().ID] = true
+	}
+	if err := p.Err(); err != nil {
+		return fmt.Errorf("error listing datasets: %w", err)
+	}
+
+	for _, id := range v.ids {
+		for _, datasetID := range componentDatasets(v.dsl.Components[id].Obj.Params) {
+			if !known[datasetID] {
+				v.report(SeverityError, DiagUnknownDataset, id, "dataset %s does not exist", datasetID)
+			}
+		}
+	}
+	return nil
+}
+
+func (v *dslValidator) verifyLLMs(ctx context.Context, client *Client) error {
+	llms, err := client.GetMyLLMs(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing LLMs: %w", err)
+	}
+
+	for _, id := range v.ids {
+		params := v.dsl.Components[id].Obj.Params
+		if llmID, ok := componentLLM(params); ok && llmID != "" && !llms.has(llmID) {
+			v.report(SeverityError, DiagUnknownLLM, id, "LLM %s is not configured", llmID)
+		}
+		if rerankID := componentRerankModel(params); rerankID != "" && !llms.has(rerankID) {
+			v.report(SeverityError, DiagUnknownLLM, id, "rerank model %s is not configured", rerankID)
+		}
+	}
+	return nil
+}
+
+// has reports whether an LLM ID, a model name optionally followed by @ and
+// the factory name, names one of the configured models.
+func (r MyLLMsResponse) has(llmID string) bool {
+	name, factory := llmID, ""
+	if i := strings.LastIndex(llmID, "@"); i >= 0 {
+		name, factory = llmID[:i], llmID[i+1:]
+	}
+
+	for providerName, provider := range r {
+		if factory != "" && providerName != factory {
+			continue
+		}
+		for _, llm := range provider.LLMs {
+			if llm.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// componentLLM returns the LLM ID of components that need one.
+func componentLLM(params ComponentParams) (string, bool) {
+	switch p := paramsPointer(params).(type) {
+	case *GenerateParams:
+		return p.LLMID, true
+	case *CategorizeParams:
+		return p.LLMID, true
+	case *RewriteQuestionParams:
+		return p.LLMID, true
+	case *KeywordExtractParams:
+		return p.LLMID, true
+	}
+	return "", false
+}
+
+func componentRerankModel(params ComponentParams) string {
+	if p, ok := paramsPointer(params).(*RetrievalParams); ok {
+		return p.RerankID
+	}
+	return ""
+}
+
+func componentDatasets(params ComponentParams) []string {
+	if p, ok := paramsPointer(params).(*RetrievalParams); ok {
+		return p.DatasetIDs
+	}
+	return nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
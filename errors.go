@@ -1,15 +1,152 @@
 package ragflow
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Sentinel errors classifying API failures. An *APIError matches them with
+// errors.Is based on its HTTP status and the code in the response body:
+//
+//	if errors.Is(err, ragflow.ErrNotFound) {
+//		...
+//	}
+//
+// RAGFlow reports most failures concerning a resource, including missing
+// ones, with code 102. Those match ErrDataError, and ErrNotFound as well when
+// the message says that the resource does not exist or is not yours.
+var (
+	ErrBadRequest          = errors.New("ragflow: bad request")
+	ErrUnauthorized        = errors.New("ragflow: unauthorized")
+	ErrForbidden           = errors.New("ragflow: forbidden")
+	ErrNotFound            = errors.New("ragflow: not found")
+	ErrDataError           = errors.New("ragflow: data error")
+	ErrDuplicateName       = errors.New("ragflow: duplicate name")
+	ErrUnsupportedFileType = errors.New("ragflow: unsupported file type")
+	ErrRateLimited         = errors.New("ragflow: rate limited")
+	ErrServer              = errors.New("ragflow: server error")
+	ErrConnection          = errors.New("ragflow: connection error")
+)
 
 type APIError struct {
 	Code       int    `json:"code"`
 	Message    string `json:"message"`
 	StatusCode int    `json:"-"`
+	// RetryAfter is the delay requested by the server's Retry-After header.
+	RetryAfter time.Duration `json:"-"`
+
+	// Method and Endpoint identify the request that failed. RequestID is the
+	// X-Request-Id response header, if the server or a proxy set one.
+	Method    string `json:"-"`
+	Endpoint  string `json:"-"`
+	RequestID string `json:"-"`
 }
 
 func (e *APIError) Error() string {
-	return fmt.Sprintf("RAGFlow API error (code: %d, status: %d): %s", e.Code, e.StatusCode, e.Message)
+	msg := fmt.Sprintf("RAGFlow API error (code: %d, status: %d", e.Code, e.StatusCode)
+	if e.Method != "" {
+		msg += fmt.Sprintf(", %s %s", e.Method, e.Endpoint)
+	}
+	if e.RequestID != "" {
+		msg += ", request id: " + e.RequestID
+	}
+	return msg + "): " + e.Message
+}
+
+// Is reports whether target is the sentinel error matching e's status or code.
+func (e *APIError) Is(target error) bool {
+	if target == nil {
+		return false
+	}
+	if target == ErrNotFound && e.Code == ErrorCodeDataError && isNotFoundMessage(e.Message) {
+		return true
+	}
+	return target == statusSentinel(e.StatusCode) || target == codeSentinel(e.Code)
+}
+
+// notFoundMessages are fragments of the messages RAGFlow sends with code 102
+// for resources that do not exist or belong to another tenant, which it does
+// not tell apart.
+var notFoundMessages = []string{
+	"don't own",
+	"doesn't own",
+	"do not own",
+	"cannot access",
+	"can't find",
+	"not found",
+	"doesn't exist",
+	"does not exist",
+}
+
+func isNotFoundMessage(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, fragment := range notFoundMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func statusSentinel(status int) error {
+	switch {
+	case status == http.StatusBadRequest:
+		return ErrBadRequest
+	case status == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case status == http.StatusForbidden:
+		return ErrForbidden
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status >= 500:
+		return ErrServer
+	}
+	return nil
+}
+
+func codeSentinel(code int) error {
+	switch code {
+	case ErrorCodeArgumentError, ErrorCodeBadRequest:
+		return ErrBadRequest
+	case ErrorCodeAuthenticationError, ErrorCodeUnauthorized:
+		return ErrUnauthorized
+	case ErrorCodePermissionError, ErrorCodeForbidden:
+		return ErrForbidden
+	case ErrorCodeNotFound:
+		return ErrNotFound
+	case ErrorCodeDataError:
+		return ErrDataError
+	case ErrorCodeDuplicatedName:
+		return ErrDuplicateName
+	case ErrorCodeFileTypeNotSupported:
+		return ErrUnsupportedFileType
+	case ErrorCodeTooManyRequests:
+		return ErrRateLimited
+	case ErrorCodeInternalServerError, ErrorCodeExceptionError:
+		return ErrServer
+	case ErrorCodeConnectionError:
+		return ErrConnection
+	}
+	return nil
+}
+
+// Retryable reports whether the request that produced the error may succeed
+// if sent again.
+func (e *APIError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	switch e.Code {
+	case ErrorCodeTooManyRequests, ErrorCodeConnectionError:
+		return true
+	}
+	return false
 }
 
 type ErrorResponse struct {
@@ -18,10 +155,18 @@ type ErrorResponse struct {
 }
 
 const (
+	ErrorCodeExceptionError       = 100
+	ErrorCodeArgumentError        = 101
+	ErrorCodeDataError            = 102
+	ErrorCodeOperatingError       = 103
+	ErrorCodeConnectionError      = 105
+	ErrorCodePermissionError      = 108
+	ErrorCodeAuthenticationError  = 109
 	ErrorCodeBadRequest           = 400
 	ErrorCodeUnauthorized         = 401
 	ErrorCodeForbidden            = 403
 	ErrorCodeNotFound             = 404
+	ErrorCodeTooManyRequests      = 429
 	ErrorCodeInternalServerError  = 500
 	ErrorCodeDuplicatedName       = 1001
 	ErrorCodeFileTypeNotSupported = 1002
@@ -29,9 +174,32 @@ const (
 	ErrorCodeGenericSuccess       = 0
 )
 
+// IsErrorCode reports whether err, or an error it wraps, is an *APIError with
+// the given body code.
 func IsErrorCode(err error, code int) bool {
-	if apiErr, ok := err.(*APIError); ok {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
 		return apiErr.Code == code
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// newAPIError builds an *APIError for a failed request. resp may be nil for
+// errors reported inside a response stream.
+func newAPIError(req *http.Request, resp *http.Response, code int, message string) *APIError {
+	apiErr := &APIError{
+		Code:       code,
+		Message:    message,
+		StatusCode: http.StatusOK,
+	}
+	if req != nil {
+		apiErr.Method = req.Method
+		apiErr.Endpoint = req.URL.Path
+	}
+	if resp != nil {
+		apiErr.StatusCode = resp.StatusCode
+		apiErr.RequestID = resp.Header.Get("X-Request-Id")
+		apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+	return apiErr
+}
@@ -0,0 +1,50 @@
+package ragflow_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+	"github.com/kevinroleke/ragflow-go/ragflowtest"
+)
+
+func TestNotFoundFromDataErrorCode(t *testing.T) {
+	srv := ragflowtest.NewServer()
+	defer srv.Close()
+	client := srv.NewClient()
+
+	_, err := client.GetDataset(context.Background(), "nope")
+	if !ragflow.IsErrorCode(err, ragflow.ErrorCodeDataError) {
+		t.Fatalf("GetDataset error = %v, want code %d", err, ragflow.ErrorCodeDataError)
+	}
+	if !errors.Is(err, ragflow.ErrNotFound) {
+		t.Errorf("errors.Is(%v, ErrNotFound) = false", err)
+	}
+	if !errors.Is(err, ragflow.ErrDataError) {
+		t.Errorf("errors.Is(%v, ErrDataError) = false", err)
+	}
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		err    *ragflow.APIError
+		target error
+		want   bool
+	}{
+		{&ragflow.APIError{Code: 102, StatusCode: 200, Message: "You don't own the dataset nope."}, ragflow.ErrNotFound, true},
+		{&ragflow.APIError{Code: 102, StatusCode: 200, Message: "You cannot access the agent nope"}, ragflow.ErrNotFound, true},
+		{&ragflow.APIError{Code: 102, StatusCode: 200, Message: "Document not found!"}, ragflow.ErrNotFound, true},
+		{&ragflow.APIError{Code: 102, StatusCode: 200, Message: "Duplicated chat name in creating chat."}, ragflow.ErrNotFound, false},
+		{&ragflow.APIError{Code: 102, StatusCode: 200, Message: "Duplicated chat name in creating chat."}, ragflow.ErrDataError, true},
+		{&ragflow.APIError{Code: 404, StatusCode: 200}, ragflow.ErrNotFound, true},
+		{&ragflow.APIError{StatusCode: 404}, ragflow.ErrNotFound, true},
+		{&ragflow.APIError{Code: 109, StatusCode: 200}, ragflow.ErrUnauthorized, true},
+		{&ragflow.APIError{Code: 101, StatusCode: 200, Message: "`name` not found"}, ragflow.ErrNotFound, false},
+	}
+	for _, tt := range tests {
+		if got := errors.Is(tt.err, tt.target); got != tt.want {
+			t.Errorf("errors.Is(%v, %v) = %v, want %v", tt.err, tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package ragflow
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kevinroleke/ragflow-go/internal/redact"
+)
+
+// maxLoggedBody caps how much of a request or response body is logged.
+const maxLoggedBody = 2048
+
+const redacted = redact.Mask
+
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+func (c *Client) logEnabled(ctx context.Context, level slog.Level) bool {
+	return c.Logger != nil && c.Logger.Enabled(ctx, level)
+}
+
+// roundTrip sends a single request through the HTTP client, logging the
+// request and the response status when a logger is configured.
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if c.logEnabled(ctx, slog.LevelDebug) {
+		attrs := []any{
+			slog.String("method", req.Method),
+			slog.String("url", req.URL.String()),
+			slog.Any("headers", redactHeaders(req.Header)),
+		}
+		if body := requestBody(req); body != "" {
+			attrs = append(attrs, slog.String("body", body))
+		}
+		c.Logger.DebugContext(ctx, "ragflow request", attrs...)
+	}
+
+	start := time.Now()
+	resp, err := c.HTTPClient.Do(req)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		if c.logEnabled(ctx, slog.LevelWarn) {
+			c.Logger.WarnContext(ctx, "ragflow request failed",
+				slog.String("method", req.Method),
+				slog.String("url", req.URL.String()),
+				slog.Duration("elapsed", elapsed),
+				slog.Any("error", err),
+			)
+		}
+		return nil, err
+	}
+
+	level := slog.LevelDebug
+	if resp.StatusCode >= 400 {
+		level = slog.LevelWarn
+	}
+	if c.logEnabled(ctx, level) {
+		c.Logger.Log(ctx, level, "ragflow response",
+			slog.String("method", req.Method),
+			slog.String("url", req.URL.String()),
+			slog.Int("status", resp.StatusCode),
+			slog.Duration("elapsed", elapsed),
+			slog.Any("headers", redactHeaders(resp.Header)),
+		)
+	}
+
+	return resp, nil
+}
+
+func (c *Client) logResponseBody(req *http.Request, body []byte) {
+	ctx := req.Context()
+	if !c.logEnabled(ctx, slog.LevelDebug) {
+		return
+	}
+	c.Logger.DebugContext(ctx, "ragflow response body",
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+		slog.String("body", truncateBody(redact.JSON(body))),
+	)
+}
+
+func (c *Client) logRetry(req *http.Request, attempt int, delay time.Duration, err error) {
+	ctx := req.Context()
+	if !c.logEnabled(ctx, slog.LevelInfo) {
+		return
+	}
+	c.Logger.InfoContext(ctx, "retrying ragflow request",
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+		slog.Int("attempt", attempt),
+		slog.Duration("delay", delay),
+		slog.Any("error", err),
+	)
+}
+
+func redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for key := range h {
+		out[key] = h.Get(key)
+	}
+	for _, key := range sensitiveHeaders {
+		if _, ok := out[key]; ok {
+			out[key] = redacted
+		}
+	}
+	return out
+}
+
+// requestBody returns a loggable copy of a JSON request body without
+// consuming it. Multipart uploads and other payloads are not logged.
+func requestBody(req *http.Request) string {
+	if req.GetBody == nil || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		return ""
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, 64*maxLoggedBody))
+	if err != nil {
+		return ""
+	}
+	return truncateBody(redact.JSON(data))
+}
+
+func truncateBody(body []byte) string {
+	if len(body) <= maxLoggedBody {
+		return string(body)
+	}
+	return string(body[:maxLoggedBody]) + "...(truncated)"
+}
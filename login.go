@@ -0,0 +1,185 @@
+package ragflow
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// DefaultLoginPublicKey is the public key of RAGFlow's web frontend, used to
+// encrypt the password sent to /v1/user/login unless another key is
+// configured.
+const DefaultLoginPublicKey = `-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEArq9XTUSeYr2+N1h3Afl/z8Dse/2yD0ZGrKwx+EEEcdsBLca9Ynmx3nIB5obmLlSfmskLpBo0UACBmB5rEjBp2Q2f3AG3Hjd4B+gNCG6BDaawuDlgANIhGnaTLrIqWrrcm4EMzJOnAOI1fgzJRsOOUEfaS318Eq9OVO3apEyCCt0lOQK6PuksduOjVxtltDav+guVAA068NrPYmRNabVKRNLJpL8w4D44sfth5RvZ3q9t+6RTArpEtc5sh5ChzvqPOzKGMXW83C95TxmXqpbK6olN4RevSfVjEAgCydH6HN6OhtOQEcnrU97r9H0iZOWwbw3pVrZiUkuRD1R56Wzs2wIDAQAB
+-----END PUBLIC KEY-----`
+
+// PublicKeySource looks up the login public key of a deployment. It returns
+// the key in PEM form or as the bare base64 body of a PEM block, as found in
+// the frontend's JavaScript.
+type PublicKeySource func(ctx context.Context) (string, error)
+
+// WithLoginPublicKey encrypts login passwords with pemKey instead of
+// DefaultLoginPublicKey, for deployments that rotated the frontend key.
+func WithLoginPublicKey(pemKey string) ClientOption {
+	return func(c *Client) {
+		c.loginKeySource = func(context.Context) (string, error) {
+			return pemKey, nil
+		}
+	}
+}
+
+// WithLoginPublicKeySource looks up the login public key with source before
+// the first login. The key is cached for later logins.
+func WithLoginPublicKeySource(source PublicKeySource) ClientOption {
+	return func(c *Client) {
+		c.loginKeySource = source
+	}
+}
+
+// WithPlainPasswordLogin sends the password unencrypted, for instances behind
+// a proxy that encrypts it. Only use it over TLS.
+func WithPlainPasswordLogin() ClientOption {
+	return func(c *Client) {
+		c.plainPasswordLogin = true
+	}
+}
+
+// PublicKeyFromFile reads the login public key from a file.
+func PublicKeyFromFile(path string) PublicKeySource {
+	return func(context.Context) (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("error reading public key: %w", err)
+		}
+		return string(data), nil
+	}
+}
+
+// PublicKeyFromURL fetches the login public key from url. The response may be
+// the key itself or a document embedding it, such as the frontend's
+// JavaScript bundle.
+func PublicKeyFromURL(url string, httpClient *http.Client) PublicKeySource {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return func(ctx context.Context) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return "", fmt.Errorf("error creating request: %w", err)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("error fetching public key: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 400 {
+			return "", fmt.Errorf("error fetching public key: HTTP %d", resp.StatusCode)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("error reading public key: %w", err)
+		}
+
+		key := findPublicKey(string(body))
+		if key == "" {
+			return "", fmt.Errorf("no public key found at %s", url)
+		}
+		return key, nil
+	}
+}
+
+var (
+	pemKeyPattern  = regexp.MustCompile(`-----BEGIN (?:RSA )?PUBLIC KEY-----[A-Za-z0-9+/=\s\\n]+-----END (?:RSA )?PUBLIC KEY-----`)
+	bareKeyPattern = regexp.MustCompile(`MII[A-Za-z0-9+/]{100,}={0,2}`)
+)
+
+// findPublicKey extracts a public key from text, either as a PEM block or as
+// the bare base64 body of one.
+func findPublicKey(text string) string {
+	if key := pemKeyPattern.FindString(text); key != "" {
+		return strings.ReplaceAll(key, `\n`, "\n")
+	}
+	return bareKeyPattern.FindString(text)
+}
+
+// ParseLoginPublicKey parses an RSA public key in PKIX or PKCS #1 form, PEM
+// encoded or as the bare base64 body of a PEM block.
+func ParseLoginPublicKey(key string) (*rsa.PublicKey, error) {
+	var der []byte
+	if block, _ := pem.Decode([]byte(key)); block != nil {
+		der = block.Bytes
+	} else {
+		var err error
+		der, err = base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse PEM block")
+		}
+	}
+
+	if pub, err := x509.ParsePKIXPublicKey(der); err == nil {
+		rsaPub, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("not an RSA public key")
+		}
+		return rsaPub, nil
+	}
+
+	pub, err := x509.ParsePKCS1PublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	return pub, nil
+}
+
+// loginPassword returns the password as RAGFlow's login form sends it.
+// The caller must hold loginMu.
+func (c *Client) loginPassword(ctx context.Context) (string, error) {
+	if c.plainPasswordLogin {
+		return c.Password, nil
+	}
+
+	if c.loginKey == nil {
+		key := DefaultLoginPublicKey
+		if c.loginKeySource != nil {
+			var err error
+			if key, err = c.loginKeySource(ctx); err != nil {
+				return "", err
+			}
+		}
+
+		pub, err := ParseLoginPublicKey(key)
+		if err != nil {
+			return "", err
+		}
+		c.loginKey = pub
+	}
+
+	return encryptPassword(c.loginKey, c.Password)
+}
+
+// encryptPassword encrypts password the way the frontend's JSEncrypt does:
+// the base64-encoded password is encrypted with RSA PKCS #1 v1.5 and the
+// result is base64 encoded.
+func encryptPassword(pub *rsa.PublicKey, password string) (string, error) {
+	encodedPassword := base64.StdEncoding.EncodeToString([]byte(password))
+
+	encrypted, err := rsa.EncryptPKCS1v15(rand.Reader, pub, []byte(encodedPassword))
+	if err != nil {
+		return "", fmt.Errorf("encryption failed: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(encrypted), nil
+}
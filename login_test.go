@@ -0,0 +1,150 @@
+package ragflow_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+	"github.com/kevinroleke/ragflow-go/ragflowtest"
+)
+
+func TestLoginWithPublicKey(t *testing.T) {
+	srv := ragflowtest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+
+	client := ragflow.NewClient(srv.APIKey,
+		ragflow.WithBaseURL(srv.URL),
+		ragflow.WithLoginPublicKey(srv.PublicKeyPEM()),
+		ragflow.WithCredentials(ragflowtest.DefaultEmail, ragflowtest.DefaultPassword),
+	)
+	if _, _, err := client.Login(ctx); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	// The fake cannot decrypt passwords encrypted with RAGFlow's own key.
+	client = ragflow.NewClient(srv.APIKey,
+		ragflow.WithBaseURL(srv.URL),
+		ragflow.WithCredentials(ragflowtest.DefaultEmail, ragflowtest.DefaultPassword),
+	)
+	if _, _, err := client.Login(ctx); err == nil {
+		t.Fatal("Login with the default key succeeded")
+	}
+}
+
+func TestPlainPasswordLogin(t *testing.T) {
+	srv := ragflowtest.NewServer(ragflowtest.WithPlainPasswords())
+	defer srv.Close()
+	ctx := context.Background()
+
+	client := ragflow.NewClient(srv.APIKey,
+		ragflow.WithBaseURL(srv.URL),
+		ragflow.WithPlainPasswordLogin(),
+		ragflow.WithCredentials(ragflowtest.DefaultEmail, ragflowtest.DefaultPassword),
+	)
+	if _, _, err := client.Login(ctx); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	client = ragflow.NewClient(srv.APIKey,
+		ragflow.WithBaseURL(srv.URL),
+		ragflow.WithPlainPasswordLogin(),
+		ragflow.WithCredentials(ragflowtest.DefaultEmail, "wrong"),
+	)
+	if _, _, err := client.Login(ctx); !errors.Is(err, ragflow.ErrUnauthorized) {
+		t.Fatalf("Login with a wrong password = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestPublicKeyFromURL(t *testing.T) {
+	srv := ragflowtest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+
+	// The frontend bundle embeds the key in a string literal with escaped
+	// newlines.
+	escaped := strings.ReplaceAll(strings.TrimSpace(srv.PublicKeyPEM()), "\n", `\n`)
+	js := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Write([]byte(`(function(){var e=new JSEncrypt;e.setPublicKey("` + escaped + `");return e})();`))
+	}))
+	defer js.Close()
+
+	key, err := ragflow.PublicKeyFromURL(js.URL+"/umi.js", nil)(ctx)
+	if err != nil {
+		t.Fatalf("PublicKeyFromURL: %v", err)
+	}
+	got, err := ragflow.ParseLoginPublicKey(key)
+	if err != nil {
+		t.Fatalf("ParseLoginPublicKey: %v", err)
+	}
+	want, err := ragflow.ParseLoginPublicKey(srv.PublicKeyPEM())
+	if err != nil {
+		t.Fatalf("ParseLoginPublicKey: %v", err)
+	}
+	if !got.Equal(want) {
+		t.Fatal("PublicKeyFromURL returned a different key")
+	}
+
+	client := ragflow.NewClient(srv.APIKey,
+		ragflow.WithBaseURL(srv.URL),
+		ragflow.WithLoginPublicKeySource(ragflow.PublicKeyFromURL(js.URL+"/umi.js", nil)),
+		ragflow.WithCredentials(ragflowtest.DefaultEmail, ragflowtest.DefaultPassword),
+	)
+	if _, _, err := client.Login(ctx); err != nil {
+		t.Fatalf("Login with the fetched key: %v", err)
+	}
+
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("console.log('no key here')"))
+	}))
+	defer empty.Close()
+	if _, err := ragflow.PublicKeyFromURL(empty.URL, nil)(ctx); err == nil {
+		t.Fatal("PublicKeyFromURL found a key in a document without one")
+	}
+}
+
+func TestParseLoginPublicKey(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &priv.PublicKey
+	pkix, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1 := x509.MarshalPKCS1PublicKey(pub)
+
+	inputs := map[string]string{
+		"PKIX PEM":      string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix})),
+		"PKCS#1 PEM":    string(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: pkcs1})),
+		"PKIX base64":   base64.StdEncoding.EncodeToString(pkix),
+		"PKCS#1 base64": "\n" + base64.StdEncoding.EncodeToString(pkcs1) + "\n",
+	}
+	for name, input := range inputs {
+		got, err := ragflow.ParseLoginPublicKey(input)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !got.Equal(pub) {
+			t.Errorf("%s: parsed a different key", name)
+		}
+	}
+
+	for _, input := range []string{"", "not a key", base64.StdEncoding.EncodeToString([]byte("not DER"))} {
+		if _, err := ragflow.ParseLoginPublicKey(input); err == nil {
+			t.Errorf("ParseLoginPublicKey(%q) succeeded", input)
+		}
+	}
+}
@@ -1,9 +1,10 @@
 package ragflow
 
 import (
+	"bytes"
 	"encoding/json"
-	"log"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -25,10 +26,8 @@ func (ut *UnixTime) UnmarshalJSON(data []byte) error {
 			return err
 		}
 		ut.Time = time.Unix(int64(timestamp / 1000), 0)
-		log.Println("used unix time")
 		return nil
 	}
-	log.Println("used string time")
 
 	// Try to parse as RFC3339 string
 	var timeStr string
@@ -38,7 +37,11 @@ func (ut *UnixTime) UnmarshalJSON(data []byte) error {
 
 	parsedTime, err := time.Parse(time.RFC3339, timeStr)
 	if err != nil {
-		return err
+		// Chunk timestamps are formatted as local date and time.
+		parsedTime, err = time.ParseInLocation(time.DateTime, timeStr, time.Local)
+		if err != nil {
+			return err
+		}
 	}
 	ut.Time = parsedTime
 	return nil
@@ -153,41 +156,149 @@ type UpdateDatasetRequest struct {
 }
 
 type Document struct {
-	ID          string                 `json:"id"`
-	Name        string                 `json:"name"`
-	Type        string                 `json:"type"`
-	Size        int64                  `json:"size"`
-	Token       int                    `json:"token"`
-	ChunkNumber int                    `json:"chunk_number"`
-	Progress    float64                `json:"progress"`
-	ProgressMsg string                 `json:"progress_msg"`
-	Process     string                 `json:"process"`
-	Source      string                 `json:"source"`
-	CreateTime  UnixTime               `json:"create_time"`
-	UpdateTime  UnixTime               `json:"update_time"`
-	CreatedBy   string                 `json:"created_by"`
-	Run         string                 `json:"run"`
-	Parser      map[string]interface{} `json:"parser"`
-	Location    string                 `json:"location"`
-}
-
-type Chunk struct {
 	ID           string                 `json:"id"`
-	Content      string                 `json:"content"`
-	DocumentID   string                 `json:"document_id"`
-	DocumentName string                 `json:"document_name"`
-	DatasetIDs   []string               `json:"dataset_ids"`
-	Important    bool                   `json:"important"`
+	Name         string                 `json:"name"`
+	Type         string                 `json:"type"`
+	Size         int64                  `json:"size"`
+	Token        int                    `json:"token"`
+	ChunkNumber  int                    `json:"chunk_number"`
+	ChunkCount   int                    `json:"chunk_count"`
+	Progress     float64                `json:"progress"`
+	ProgressMsg  string                 `json:"progress_msg"`
+	Process      string                 `json:"process"`
+	Source       string                 `json:"source"`
 	CreateTime   UnixTime               `json:"create_time"`
 	UpdateTime   UnixTime               `json:"update_time"`
-	Positions    [][]int                `json:"positions"`
-	Available    bool                   `json:"available"`
-	TermWeights  map[string]interface{} `json:"term_weights"`
+	CreatedBy    string                 `json:"created_by"`
+	Run          string                 `json:"run"`
+	Parser       map[string]interface{} `json:"parser"`
+	Location     string                 `json:"location"`
+	ChunkMethod  string                 `json:"chunk_method"`
+	ParserConfig map[string]interface{} `json:"parser_config"`
+	MetaFields   map[string]interface{} `json:"meta_fields"`
+}
+
+type UpdateDocumentRequest struct {
+	Name         string                 `json:"name,omitempty"`
+	ChunkMethod  string                 `json:"chunk_method,omitempty"`
+	ParserConfig map[string]interface{} `json:"parser_config,omitempty"`
+	MetaFields   map[string]interface{} `json:"meta_fields,omitempty"`
+	Enabled      *bool                  `json:"enabled,omitempty"`
+	// Reparse starts parsing the document again once it is updated. RAGFlow
+	// drops a document's chunks when its chunk method or parser config
+	// changes, so set this alongside ChunkMethod to rebuild them.
+	Reparse bool `json:"-"`
+}
+
+type Chunk struct {
+	ID                string                 `json:"id"`
+	Content           string                 `json:"content"`
+	DocumentID        string                 `json:"document_id"`
+	DocumentName      string                 `json:"document_name"`
+	DatasetID         string                 `json:"dataset_id"`
+	DatasetIDs        []string               `json:"dataset_ids"`
+	Important         bool                   `json:"important"`
+	ImportantKeywords []string               `json:"important_keywords"`
+	Questions         []string               `json:"questions"`
+	ImageID           string                 `json:"image_id"`
+	CreateTime        UnixTime               `json:"create_time"`
+	UpdateTime        UnixTime               `json:"update_time"`
+	Positions         [][]int                `json:"positions"`
+	Available         bool                   `json:"available"`
+	TermWeights       map[string]interface{} `json:"term_weights"`
+}
+
+type RetrievalRequest struct {
+	Question               string   `json:"question"`
+	DatasetIDs             []string `json:"dataset_ids,omitempty"`
+	DocumentIDs            []string `json:"document_ids,omitempty"`
+	Page                   int      `json:"page,omitempty"`
+	PageSize               int      `json:"page_size,omitempty"`
+	// SimilarityThreshold and VectorSimilarityWeight are pointers so that 0
+	// can be sent; nil leaves the server default.
+	SimilarityThreshold    *float64 `json:"similarity_threshold,omitempty"`
+	VectorSimilarityWeight *float64 `json:"vector_similarity_weight,omitempty"`
+	TopK                   int      `json:"top_k,omitempty"`
+	RerankID               string   `json:"rerank_id,omitempty"`
+	Keyword                bool     `json:"keyword,omitempty"`
+	Highlight              bool     `json:"highlight,omitempty"`
+}
+
+// RetrievalChunk is a Chunk as returned by the retrieval endpoint, carrying
+// the scores it was ranked with.
+type RetrievalChunk struct {
+	Chunk
+	// DatasetID shadows Chunk.DatasetID. Servers report it as kb_id or
+	// dataset_id; both fields hold whichever was sent.
+	DatasetID        string  `json:"kb_id"`
+	DocumentKeyword  string  `json:"document_keyword"`
+	ContentLTKS      string  `json:"content_ltks"`
+	Highlight        string  `json:"highlight"`
+	Similarity       float64 `json:"similarity"`
+	VectorSimilarity float64 `json:"vector_similarity"`
+	TermSimilarity   float64 `json:"term_similarity"`
+}
+
+func (rc *RetrievalChunk) UnmarshalJSON(data []byte) error {
+	type plain RetrievalChunk
+	if err := json.Unmarshal(data, (*plain)(rc)); err != nil {
+		return err
+	}
+	if rc.DatasetID == "" {
+		rc.DatasetID = rc.Chunk.DatasetID
+	}
+	if rc.Chunk.DatasetID == "" {
+		rc.Chunk.DatasetID = rc.DatasetID
+	}
+	return nil
+}
+
+// HighlightSpans returns the fragments of the chunk that the server wrapped in
+// <em> tags when the request was made with Highlight set.
+func (rc RetrievalChunk) HighlightSpans() []string {
+	var spans []string
+	rest := rc.Highlight
+	for {
+		start := strings.Index(rest, "<em>")
+		if start < 0 {
+			return spans
+		}
+		rest = rest[start+len("<em>"):]
+		end := strings.Index(rest, "</em>")
+		if end < 0 {
+			return spans
+		}
+		spans = append(spans, rest[:end])
+		rest = rest[end+len("</em>"):]
+	}
 }
 
+type DocAgg struct {
+	DocumentID   string `json:"doc_id"`
+	DocumentName string `json:"doc_name"`
+	Count        int    `json:"count"`
+}
+
+type RetrievalResult struct {
+	Chunks  []RetrievalChunk `json:"chunks"`
+	DocAggs []DocAgg         `json:"doc_aggs"`
+	Total   int              `json:"total"`
+}
+
+type AddChunkRequest struct {
+	Content           string   `json:"content"`
+	ImportantKeywords []string `json:"important_keywords,omitempty"`
+	Questions         []string `json:"questions,omitempty"`
+}
+
+// UpdateChunkRequest changes the fields that are set. ImportantKeywords and
+// Questions are pointers so that the lists can be cleared with an empty
+// slice; nil leaves them unchanged.
 type UpdateChunkRequest struct {
-	Content   string `json:"content,omitempty"`
-	Important bool   `json:"important,omitempty"`
+	Content           string    `json:"content,omitempty"`
+	ImportantKeywords *[]string `json:"important_keywords,omitempty"`
+	Questions         *[]string `json:"questions,omitempty"`
+	Available         *bool     `json:"available,omitempty"`
 }
 
 type Variable struct {
@@ -305,33 +416,143 @@ type UpdateSessionRequest struct {
 	Name string `json:"name"`
 }
 
+type ConverseRequest struct {
+	Question string `json:"question"`
+	// SessionID continues a session. Without it the server starts a new
+	// session and returns its ID in the response.
+	SessionID string `json:"session_id,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+	// Quote controls whether the answer cites its sources. The assistant's
+	// setting applies when nil.
+	Quote  *bool `json:"quote,omitempty"`
+	Stream bool  `json:"stream"`
+}
+
+type ConverseResponse struct {
+	ID        string        `json:"id"`
+	SessionID string        `json:"session_id"`
+	Answer    string        `json:"answer"`
+	Reference ChatReference `json:"reference"`
+	Prompt    string        `json:"prompt"`
+	// Delta is the text added to Answer since the previous event of a
+	// stream. It is empty for non-streaming responses.
+	Delta string `json:"-"`
+}
+
+// ChatReference lists the chunks an answer is based on and the documents
+// they come from.
+type ChatReference RetrievalResult
+
+func (r *ChatReference) UnmarshalJSON(data []byte) error {
+	// Answers without references carry an empty list or null instead of an
+	// object.
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		*r = ChatReference{}
+		return nil
+	}
+	return json.Unmarshal(data, (*RetrievalResult)(r))
+}
+
 type Agent struct {
-	ID          string                 `json:"id"`
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	Avatar      string                 `json:"avatar"`
-	Language    string                 `json:"language"`
-	DSL         map[string]interface{} `json:"dsl"`
-	CreateTime  UnixTime               `json:"create_time"`
-	UpdateTime  UnixTime               `json:"update_time"`
-	CreatedBy   string                 `json:"created_by"`
-	TenantID    string                 `json:"tenant_id"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Avatar      string   `json:"avatar"`
+	Language    string   `json:"language"`
+	DSL         *DSL     `json:"dsl"`
+	CreateTime  UnixTime `json:"create_time"`
+	UpdateTime  UnixTime `json:"update_time"`
+	CreatedBy   string   `json:"created_by"`
+	TenantID    string   `json:"tenant_id"`
+}
+
+// AgentSession is a conversation with an agent. RAGFlow keeps a copy of the
+// agent's DSL with each session to hold the state of the run.
+type AgentSession struct {
+	ID         string        `json:"id"`
+	AgentID    string        `json:"agent_id"`
+	UserID     string        `json:"user_id"`
+	Source     string        `json:"source"`
+	Messages   []ChatMessage `json:"message"`
+	DSL        *DSL          `json:"dsl,omitempty"`
+	CreateTime UnixTime      `json:"create_time"`
+	UpdateTime UnixTime      `json:"update_time"`
+}
+
+// RunAgentRequest is a question to an agent with the inputs of its Begin
+// component.
+type RunAgentRequest struct {
+	Question  string
+	SessionID string
+	// UserID is an identifier of the user of your application.
+	UserID string
+	// Inputs are the values of the Begin component's parameters, by key.
+	Inputs map[string]AgentInput
+	// Files are sent with the question. Upload them with UploadAgentFile,
+	// or list them in Attachments to have them uploaded first.
+	Files       []AgentFile
+	Attachments []UploadFile
+}
+
+// AgentInput is the value of a Begin component parameter, tagged with the
+// parameter's type. Build it with TextInput, FileInput and the like.
+type AgentInput struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+func TextInput(value string) AgentInput {
+	return AgentInput{Type: "line", Value: value}
+}
+
+func ParagraphInput(value string) AgentInput {
+	return AgentInput{Type: "paragraph", Value: value}
+}
+
+// OptionInput selects one of the options of an options parameter.
+func OptionInput(value string) AgentInput {
+	return AgentInput{Type: "options", Value: value}
+}
+
+func IntegerInput(value int) AgentInput {
+	return AgentInput{Type: "integer", Value: value}
+}
+
+func BooleanInput(value bool) AgentInput {
+	return AgentInput{Type: "boolean", Value: value}
+}
+
+// FileInput passes files uploaded with UploadAgentFile.
+func FileInput(files ...AgentFile) AgentInput {
+	return AgentInput{Type: "file", Value: files}
+}
+
+// AgentFile is a file uploaded for use in agent runs.
+type AgentFile struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	Extension  string `json:"extension"`
+	MimeType   string `json:"mime_type"`
+	CreatedBy  string `json:"created_by"`
+	PreviewURL string `json:"preview_url,omitempty"`
 }
 
 type CreateAgentRequest struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description,omitempty"`
-	Avatar      string                 `json:"avatar,omitempty"`
-	Language    string                 `json:"language,omitempty"`
-	DSL         map[string]interface{} `json:"dsl,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Avatar      string `json:"avatar,omitempty"`
+	Language    string `json:"language,omitempty"`
+	DSL         *DSL   `json:"dsl,omitempty"`
 }
 
 type UpdateAgentRequest struct {
-	Name        string                 `json:"name,omitempty"`
-	Description string                 `json:"description,omitempty"`
-	Avatar      string                 `json:"avatar,omitempty"`
-	Language    string                 `json:"language,omitempty"`
-	DSL         map[string]interface{} `json:"dsl,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Avatar      string `json:"avatar,omitempty"`
+	Language    string `json:"language,omitempty"`
+	DSL         *DSL   `json:"dsl,omitempty"`
 }
 
 type DocumentsList struct {
@@ -343,6 +564,16 @@ type DocumentsList struct {
 	} `json:"data"`
 }
 
+type ChunksList struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Total    int      `json:"total"`
+		Items    []Chunk  `json:"chunks"`
+		Document Document `json:"doc"`
+	} `json:"data"`
+}
+
 type ListResponse[T any] struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
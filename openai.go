@@ -5,7 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"net/http"
 	"strings"
 )
 
@@ -45,19 +45,13 @@ func (c *Client) CreateChatCompletionStream(ctx context.Context, req ChatComplet
 			return
 		}
 
-		resp, err := c.HTTPClient.Do(httpReq)
+		resp, err := c.send(httpReq)
 		if err != nil {
-			errChan <- fmt.Errorf("error making request: %w", err)
+			errChan <- err
 			return
 		}
 		defer resp.Body.Close()
 
-		if resp.StatusCode >= 400 {
-			bodyBytes, _ := io.ReadAll(resp.Body)
-			errChan <- c.handleErrorResponse(resp.StatusCode, bodyBytes)
-			return
-		}
-
 		scanner := bufio.NewScanner(resp.Body)
 		for scanner.Scan() {
 			line := strings.TrimSpace(scanner.Text())
@@ -79,12 +73,8 @@ func (c *Client) CreateChatCompletionStream(ctx context.Context, req ChatComplet
 			}
 
 			// Check if this is an error response (non-streaming)
-			var errorCheck struct {
-				Code    int    `json:"code"`
-				Message string `json:"message"`
-			}
-			if err := json.Unmarshal([]byte(data), &errorCheck); err == nil && errorCheck.Code != 0 {
-				errChan <- fmt.Errorf("API error %d: %s", errorCheck.Code, errorCheck.Message)
+			if err := streamError(httpReq, []byte(data)); err != nil {
+				errChan <- err
 				return
 			}
 
@@ -108,3 +98,17 @@ func (c *Client) CreateChatCompletionStream(ctx context.Context, req ChatComplet
 
 	return respChan, errChan
 }
+
+// streamError returns the error reported by an event of a response stream.
+// RAGFlow reports failures inside the stream as {"code": ..., "message": ...}
+// events after the HTTP status has already been sent.
+func streamError(req *http.Request, data []byte) error {
+	var event struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil || event.Code == 0 || event.Code == 200 {
+		return nil
+	}
+	return newAPIError(req, nil, event.Code, event.Message)
+}
@@ -0,0 +1,276 @@
+package ragflow
+
+import "context"
+
+const defaultPageSize = 30
+
+// PageFunc fetches one page of results. It returns the items of the page and
+// the total number of items, or zero if the endpoint does not report a total.
+type PageFunc[T any] func(ctx context.Context, page, pageSize int) ([]T, int, error)
+
+type PagerOptions struct {
+	// MaxItems stops the iteration after this many items. Zero means no limit.
+	MaxItems int
+	// Prefetch fetches the next page in the background while the current one
+	// is being consumed.
+	Prefetch bool
+}
+
+type pageResult[T any] struct {
+	items []T
+	total int
+	err   error
+}
+
+// Pager iterates over a paginated list endpoint one item at a time:
+//
+//	p := client.DatasetsPager(nil, nil)
+//	for p.Next(ctx) {
+//		ds := p.Item()
+//		...
+//	}
+//	if err := p.Err(); err != nil {
+//		...
+//	}
+//
+// A Pager is not safe for concurrent use.
+type Pager[T any] struct {
+	fetch    PageFunc[T]
+	pageSize int
+	maxItems int
+	prefetch bool
+
+	page    int
+	offset  int
+	items   []T
+	index   int
+	yielded int
+	item    T
+	err     error
+	last    bool
+	pending chan pageResult[T]
+}
+
+// NewPager returns a Pager that starts at startPage and requests pageSize
+// items per page. Non-positive values select the first page and the default
+// page size.
+func NewPager[T any](fetch PageFunc[T], startPage, pageSize int, opts *PagerOptions) *Pager[T] {
+	if startPage <= 0 {
+		startPage = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	p := &Pager[T]{
+		fetch:    fetch,
+		pageSize: pageSize,
+		page:     startPage,
+		offset:   (startPage - 1) * pageSize,
+	}
+	if opts != nil {
+		p.maxItems = opts.MaxItems
+		p.prefetch = opts.Prefetch
+	}
+	return p
+}
+
+// Next advances to the next item, fetching a new page when needed. It returns
+// false when the items are exhausted, MaxItems is reached or an error occurs.
+func (p *Pager[T]) Next(ctx context.Context) bool {
+	if p.err != nil || (p.maxItems > 0 && p.yielded >= p.maxItems) {
+		return false
+	}
+
+	for p.index >= len(p.items) {
+		if p.last {
+			return false
+		}
+		if err := p.nextPage(ctx); err != nil {
+			p.err = err
+			return false
+		}
+	}
+
+	p.item = p.items[p.index]
+	p.index++
+	p.yielded++
+	return true
+}
+
+// Item returns the current item.
+func (p *Pager[T]) Item() T {
+	return p.item
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (p *Pager[T]) Err() error {
+	return p.err
+}
+
+// All collects the remaining items.
+func (p *Pager[T]) All(ctx context.Context) ([]T, error) {
+	var all []T
+	for p.Next(ctx) {
+		all = append(all, p.Item())
+	}
+	return all, p.Err()
+}
+
+func (p *Pager[T]) nextPage(ctx context.Context) error {
+	var res pageResult[T]
+	if p.pending != nil {
+		select {
+		case res = <-p.pending:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		p.pending = nil
+	} else {
+		res.items, res.total, res.err = p.fetch(ctx, p.page, p.pageSize)
+	}
+	if res.err != nil {
+		return res.err
+	}
+
+	p.page++
+	p.offset += len(res.items)
+	p.items = res.items
+	p.index = 0
+
+	p.last = len(res.items) < p.pageSize ||
+		(res.total > 0 && p.offset >= res.total) ||
+		(p.maxItems > 0 && p.yielded+len(res.items) >= p.maxItems)
+
+	if p.prefetch && !p.last {
+		pending := make(chan pageResult[T], 1)
+		page := p.page
+		go func() {
+			var r pageResult[T]
+			r.items, r.total, r.err = p.fetch(ctx, page, p.pageSize)
+			pending <- r
+		}()
+		p.pending = pending
+	}
+
+	return nil
+}
+
+func (c *Client) DatasetsPager(opts *ListDatasetsOptions, pagerOpts *PagerOptions) *Pager[Dataset] {
+	var o ListDatasetsOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	return NewPager(func(ctx context.Context, page, pageSize int) ([]Dataset, int, error) {
+		o := o
+		o.Page, o.PageSize = page, pageSize
+		resp, err := c.ListDatasets(ctx, &o)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.Data, 0, nil
+	}, o.Page, o.PageSize, pagerOpts)
+}
+
+func (c *Client) DocumentsPager(datasetID string, opts *ListDocumentsOptions, pagerOpts *PagerOptions) *Pager[Document] {
+	var o ListDocumentsOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	return NewPager(func(ctx context.Context, page, pageSize int) ([]Document, int, error) {
+		o := o
+		o.Page, o.PageSize = page, pageSize
+		resp, err := c.ListDocuments(ctx, datasetID, &o)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.Data.Items, resp.Data.Total, nil
+	}, o.Page, o.PageSize, pagerOpts)
+}
+
+func (c *Client) ChunksPager(datasetID, documentID string, opts *ListChunksOptions, pagerOpts *PagerOptions) *Pager[Chunk] {
+	var o ListChunksOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	return NewPager(func(ctx context.Context, page, pageSize int) ([]Chunk, int, error) {
+		o := o
+		o.Page, o.PageSize = page, pageSize
+		resp, err := c.ListChunks(ctx, datasetID, documentID, &o)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.Data.Items, resp.Data.Total, nil
+	}, o.Page, o.PageSize, pagerOpts)
+}
+
+func (c *Client) AssistantsPager(opts *ListAssistantsOptions, pagerOpts *PagerOptions) *Pager[Assistant] {
+	var o ListAssistantsOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	return NewPager(func(ctx context.Context, page, pageSize int) ([]Assistant, int, error) {
+		o := o
+		o.Page, o.PageSize = page, pageSize
+		resp, err := c.ListAssistants(ctx, &o)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.Data.Items, resp.Data.Total, nil
+	}, o.Page, o.PageSize, pagerOpts)
+}
+
+func (c *Client) SessionsPager(assistantID string, opts *ListSessionsOptions, pagerOpts *PagerOptions) *Pager[Session] {
+	var o ListSessionsOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	return NewPager(func(ctx context.Context, page, pageSize int) ([]Session, int, error) {
+		o := o
+		o.Page, o.PageSize = page, pageSize
+		resp, err := c.ListSessions(ctx, assistantID, &o)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.Data.Items, resp.Data.Total, nil
+	}, o.Page, o.PageSize, pagerOpts)
+}
+
+func (c *Client) AgentSessionsPager(agentID string, opts *ListAgentSessionsOptions, pagerOpts *PagerOptions) *Pager[AgentSession] {
+	var o ListAgentSessionsOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	return NewPager(func(ctx context.Context, page, pageSize int) ([]AgentSession, int, error) {
+		o := o
+		o.Page, o.PageSize = page, pageSize
+		resp, err := c.ListAgentSessions(ctx, agentID, &o)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.Data.Items, resp.Data.Total, nil
+	}, o.Page, o.PageSize, pagerOpts)
+}
+
+func (c *Client) AgentsPager(opts *ListAgentsOptions, pagerOpts *PagerOptions) *Pager[Agent] {
+	var o ListAgentsOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	return NewPager(func(ctx context.Context, page, pageSize int) ([]Agent, int, error) {
+		o := o
+		o.Page, o.PageSize = page, pageSize
+		resp, err := c.ListAgents(ctx, &o)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.Data.Items, resp.Data.Total, nil
+	}, o.Page, o.PageSize, pagerOpts)
+}
@@ -0,0 +1,102 @@
+package ragflow_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+)
+
+// fakePages serves n integers in pages and records the pages requested.
+type fakePages struct {
+	n           int
+	reportTotal bool
+	failPage    int
+
+	mu    sync.Mutex
+	pages []int
+}
+
+func (f *fakePages) fetch(ctx context.Context, page, pageSize int) ([]int, int, error) {
+	f.mu.Lock()
+	f.pages = append(f.pages, page)
+	f.mu.Unlock()
+
+	if page == f.failPage {
+		return nil, 0, errors.New("page failed")
+	}
+	var items []int
+	for i := (page - 1) * pageSize; i < page*pageSize && i < f.n; i++ {
+		items = append(items, i)
+	}
+	total := 0
+	if f.reportTotal {
+		total = f.n
+	}
+	return items, total, nil
+}
+
+func TestPager(t *testing.T) {
+	tests := []struct {
+		name        string
+		n           int
+		reportTotal bool
+		startPage   int
+		pageSize    int
+		opts        ragflow.PagerOptions
+		wantItems   int
+		wantFirst   int
+		wantPages   []int
+	}{
+		{name: "short last page", n: 7, pageSize: 3, wantItems: 7, wantPages: []int{1, 2, 3}},
+		{name: "stops at total", n: 6, reportTotal: true, pageSize: 3, wantItems: 6, wantPages: []int{1, 2}},
+		{name: "no total, full last page", n: 6, pageSize: 3, wantItems: 6, wantPages: []int{1, 2, 3}},
+		{name: "total of 0", n: 0, reportTotal: true, pageSize: 3, wantItems: 0, wantPages: []int{1}},
+		{name: "max items", n: 10, reportTotal: true, pageSize: 3, opts: ragflow.PagerOptions{MaxItems: 4}, wantItems: 4, wantPages: []int{1, 2}},
+		{name: "max items at page end", n: 10, pageSize: 3, opts: ragflow.PagerOptions{MaxItems: 3}, wantItems: 3, wantPages: []int{1}},
+		{name: "start page", n: 7, reportTotal: true, startPage: 2, pageSize: 3, wantItems: 4, wantFirst: 3, wantPages: []int{2, 3}},
+		{name: "default page size", n: 31, wantItems: 31, wantPages: []int{1, 2}},
+		{name: "prefetch", n: 7, pageSize: 3, opts: ragflow.PagerOptions{Prefetch: true}, wantItems: 7, wantPages: []int{1, 2, 3}},
+		{name: "prefetch stops at total", n: 6, reportTotal: true, pageSize: 3, opts: ragflow.PagerOptions{Prefetch: true}, wantItems: 6, wantPages: []int{1, 2}},
+		{name: "prefetch with max items", n: 10, pageSize: 3, opts: ragflow.PagerOptions{Prefetch: true, MaxItems: 5}, wantItems: 5, wantPages: []int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakePages{n: tt.n, reportTotal: tt.reportTotal}
+			opts := tt.opts
+			items, err := ragflow.NewPager(f.fetch, tt.startPage, tt.pageSize, &opts).All(context.Background())
+			if err != nil {
+				t.Fatalf("All: %v", err)
+			}
+			if len(items) != tt.wantItems {
+				t.Fatalf("got %d items, want %d", len(items), tt.wantItems)
+			}
+			for i, item := range items {
+				if item != tt.wantFirst+i {
+					t.Fatalf("item %d = %d, want %d", i, item, tt.wantFirst+i)
+				}
+			}
+			if !reflect.DeepEqual(f.pages, tt.wantPages) {
+				t.Errorf("fetched pages %v, want %v", f.pages, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestPagerError(t *testing.T) {
+	for _, prefetch := range []bool{false, true} {
+		f := &fakePages{n: 10, failPage: 2}
+		p := ragflow.NewPager(f.fetch, 1, 3, &ragflow.PagerOptions{Prefetch: prefetch})
+		items, err := p.All(context.Background())
+		if err == nil || len(items) != 3 {
+			t.Fatalf("prefetch %v: All = %v, %v; want the first page and an error", prefetch, items, err)
+		}
+		if p.Next(context.Background()) {
+			t.Fatalf("prefetch %v: Next succeeded after an error", prefetch)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package ragflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Document run states as reported in Document.Run.
+const (
+	RunUnstart = "UNSTART"
+	RunRunning = "RUNNING"
+	RunCancel  = "CANCEL"
+	RunDone    = "DONE"
+	RunFail    = "FAIL"
+)
+
+const (
+	defaultParsePollInterval = 2 * time.Second
+	parsePollPageSize        = 100
+	// parsePollLookups is the number of pending documents up to which each
+	// is looked up by ID instead of paging through the dataset.
+	parsePollLookups   = 10
+	stopParsingTimeout = 10 * time.Second
+)
+
+var (
+	ErrParseFailed    = errors.New("ragflow: document parsing failed")
+	ErrParseCancelled = errors.New("ragflow: document parsing cancelled")
+)
+
+// ParseError reports a document whose parsing ended in the FAIL or CANCEL
+// state. It matches ErrParseFailed or ErrParseCancelled with errors.Is.
+type ParseError struct {
+	DocumentID string
+	Name       string
+	Run        string
+	Message    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parsing document %s (%s) ended in state %s: %s", e.DocumentID, e.Name, e.Run, e.Message)
+}
+
+func (e *ParseError) Is(target error) bool {
+	switch target {
+	case ErrParseFailed:
+		return e.Run == RunFail
+	case ErrParseCancelled:
+		return e.Run == RunCancel
+	}
+	return false
+}
+
+// ParseEvent describes a change in the parsing state of one document.
+type ParseEvent struct {
+	DocumentID  string
+	Name        string
+	Run         string
+	Progress    float64
+	ProgressMsg string
+	ChunkCount  int
+	// Done is set on the last event for the document.
+	Done bool
+	// Err is set when the document failed or was cancelled.
+	Err error
+}
+
+type ParseWaitOptions struct {
+	// PollInterval is the delay between polls of the documents list.
+	PollInterval time.Duration
+	// Events, if set, receives a ParseEvent whenever a document's progress
+	// changes. Sends block, so the channel must be drained or buffered. It is
+	// closed when the wait returns.
+	Events chan<- ParseEvent
+	// StopOnCancel stops server-side parsing of the unfinished documents when
+	// ctx is cancelled or its deadline passes, instead of leaving them running.
+	StopOnCancel bool
+}
+
+// ParseDocumentsAndWait starts parsing the documents and blocks until every
+// one of them has finished, failed or been cancelled, or ctx is done. It
+// returns the final state of the documents; failures are reported as joined
+// *ParseError values.
+func (c *Client) ParseDocumentsAndWait(ctx context.Context, datasetID string, documentIDs []string, opts *ParseWaitOptions) ([]Document, error) {
+	if err := c.ParseDocuments(ctx, datasetID, documentIDs); err != nil {
+		if opts != nil && opts.Events != nil {
+			close(opts.Events)
+		}
+		return nil, err
+	}
+
+	return c.WaitForParsing(ctx, datasetID, documentIDs, opts)
+}
+
+// WaitForParsing polls the documents until every one of them has finished,
+// failed or been cancelled, without starting parsing itself.
+func (c *Client) WaitForParsing(ctx context.Context, datasetID string, documentIDs []string, opts *ParseWaitOptions) ([]Document, error) {
+	interval := defaultParsePollInterval
+	var events chan<- ParseEvent
+	stopOnCancel := false
+	if opts != nil {
+		if opts.PollInterval > 0 {
+			interval = opts.PollInterval
+		}
+		events = opts.Events
+		stopOnCancel = opts.StopOnCancel
+	}
+	if events != nil {
+		defer close(events)
+	}
+
+	pending := make(map[string]bool, len(documentIDs))
+	for _, id := range documentIDs {
+		pending[id] = true
+	}
+	if stopOnCancel {
+		defer func() {
+			if ctx.Err() != nil && len(pending) > 0 {
+				c.stopPending(ctx, datasetID, pending)
+			}
+		}()
+	}
+	last := make(map[string]ParseEvent, len(documentIDs))
+	final := make(map[string]Document, len(documentIDs))
+	var errs []error
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		docs, missing, err := c.pollDocuments(ctx, datasetID, pending)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, doc := range docs {
+			ev := parseEvent(doc)
+			if prev, seen := last[doc.ID]; seen && prev == ev {
+				continue
+			}
+			last[doc.ID] = ev
+
+			if ev.Done {
+				delete(pending, doc.ID)
+				final[doc.ID] = doc
+				if ev.Err != nil {
+					errs = append(errs, ev.Err)
+				}
+			}
+
+			if events != nil {
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+		}
+
+		for _, id := range missing {
+			delete(pending, id)
+			errs = append(errs, fmt.Errorf("document %s not found in dataset %s: %w", id, datasetID, ErrNotFound))
+		}
+
+		if len(pending) == 0 {
+			result := make([]Document, 0, len(final))
+			for _, id := range documentIDs {
+				if doc, ok := final[id]; ok {
+					result = append(result, doc)
+				}
+			}
+			return result, errors.Join(errs...)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// stopPending asks the server to stop parsing the pending documents. It runs
+// after ctx is done, so it uses a detached context with its own timeout.
+func (c *Client) stopPending(ctx context.Context, datasetID string, pending map[string]bool) {
+	ids := make([]string, 0, len(pending))
+	for id := range pending {
+		ids = append(ids, id)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), stopParsingTimeout)
+	defer cancel()
+
+	// The caller already has ctx's error to report; a failure to stop is
+	// only worth logging.
+	if err := c.StopParsingDocuments(stopCtx, datasetID, ids); err != nil && c.Logger != nil {
+		c.Logger.WarnContext(stopCtx, "failed to stop document parsing", "dataset_id", datasetID, "error", err)
+	}
+}
+
+// pollDocuments fetches the pending documents, looking each up by ID when
+// there are few and paging through the dataset otherwise. Pages shift while
+// documents are added or removed, so documents the pages miss are looked up
+// by ID too; those that no longer exist are returned as missing.
+func (c *Client) pollDocuments(ctx context.Context, datasetID string, pending map[string]bool) ([]Document, []string, error) {
+	var docs []Document
+	if len(pending) > parsePollLookups {
+		for page := 1; ; page++ {
+			list, err := c.ListDocuments(ctx, datasetID, &ListDocumentsOptions{
+				Page:     page,
+				PageSize: parsePollPageSize,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for _, doc := range list.Data.Items {
+				if pending[doc.ID] {
+					docs = append(docs, doc)
+				}
+			}
+
+			if len(docs) == len(pending) || len(list.Data.Items) < parsePollPageSize {
+				break
+			}
+		}
+	}
+
+	var missing []string
+	for id := range pending {
+		if containsDocument(docs, id) {
+			continue
+		}
+		list, err := c.ListDocuments(ctx, datasetID, &ListDocumentsOptions{ID: id})
+		switch {
+		case errors.Is(err, ErrNotFound):
+			missing = append(missing, id)
+		case err != nil:
+			return nil, nil, err
+		case len(list.Data.Items) == 0:
+			missing = append(missing, id)
+		default:
+			docs = append(docs, list.Data.Items...)
+		}
+	}
+	return docs, missing, nil
+}
+
+func parseEvent(doc Document) ParseEvent {
+	ev := ParseEvent{
+		DocumentID:  doc.ID,
+		Name:        doc.Name,
+		Run:         documentRun(doc),
+		Progress:    doc.Progress,
+		ProgressMsg: doc.ProgressMsg,
+		ChunkCount:  doc.ChunkCount,
+	}
+	if ev.ChunkCount == 0 {
+		ev.ChunkCount = doc.ChunkNumber
+	}
+
+	switch ev.Run {
+	case RunDone:
+		ev.Done = true
+	case RunFail, RunCancel:
+		ev.Done = true
+		ev.Err = &ParseError{DocumentID: doc.ID, Name: doc.Name, Run: ev.Run, Message: doc.ProgressMsg}
+	}
+	return ev
+}
+
+// documentRun normalises the run state, which older servers report as a
+// numeric string, and infers it from progress when it is missing.
+func documentRun(doc Document) string {
+	switch doc.Run {
+	case "0":
+		return RunUnstart
+	case "1":
+		return RunRunning
+	case "2":
+		return RunCancel
+	case "3":
+		return RunDone
+	case "4":
+		return RunFail
+	case "":
+		switch {
+		case doc.Progress < 0:
+			return RunFail
+		case doc.Progress >= 1:
+			return RunDone
+		case doc.Progress > 0:
+			return RunRunning
+		}
+		return RunUnstart
+	}
+	return doc.Run
+}
+
+func containsDocument(docs []Document, id string) bool {
+	for _, doc := range docs {
+		if doc.ID == id {
+			return true
+		}
+	}
+	return false
+}
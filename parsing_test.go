@@ -0,0 +1,173 @@
+package ragflow_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+	"github.com/kevinroleke/ragflow-go/ragflowtest"
+)
+
+var fastPolls = &ragflow.ParseWaitOptions{PollInterval: 5 * time.Millisecond}
+
+// uploadForParsing creates a dataset holding a document per name and
+// returns the dataset ID and the document IDs.
+func uploadForParsing(t *testing.T, client *ragflow.Client, names ...string) (string, []string) {
+	t.Helper()
+	ctx := context.Background()
+	ds, err := client.CreateDataset(ctx, ragflow.CreateDatasetRequest{Name: "parsing"})
+	if err != nil {
+		t.Fatalf("CreateDataset: %v", err)
+	}
+	var ids []string
+	for _, name := range names {
+		doc, err := client.UploadDocumentFromBytes(ctx, ds.ID, name, []byte("content of "+name))
+		if err != nil {
+			t.Fatalf("UploadDocumentFromBytes: %v", err)
+		}
+		ids = append(ids, doc.ID)
+	}
+	return ds.ID, ids
+}
+
+func documentRun(t *testing.T, client *ragflow.Client, datasetID, documentID string) string {
+	t.Helper()
+	list, err := client.ListDocuments(context.Background(), datasetID, &ragflow.ListDocumentsOptions{ID: documentID})
+	if err != nil || len(list.Data.Items) != 1 {
+		t.Fatalf("ListDocuments = %+v, %v", list, err)
+	}
+	return list.Data.Items[0].Run
+}
+
+func TestParseFailure(t *testing.T) {
+	srv := ragflowtest.NewServer(
+		ragflowtest.WithParseDuration(20*time.Millisecond),
+		ragflowtest.WithParseFailure(func(name string) bool { return name == "bad.pdf" }),
+	)
+	defer srv.Close()
+	client := srv.NewClient()
+	datasetID, ids := uploadForParsing(t, client, "good.txt", "bad.pdf")
+
+	events := make(chan ragflow.ParseEvent, 100)
+	docs, err := client.ParseDocumentsAndWait(context.Background(), datasetID, ids, &ragflow.ParseWaitOptions{
+		PollInterval: 5 * time.Millisecond,
+		Events:       events,
+	})
+	if !errors.Is(err, ragflow.ErrParseFailed) || errors.Is(err, ragflow.ErrParseCancelled) {
+		t.Fatalf("ParseDocumentsAndWait error = %v, want ErrParseFailed", err)
+	}
+	var parseErr *ragflow.ParseError
+	if !errors.As(err, &parseErr) || parseErr.DocumentID != ids[1] || parseErr.Run != ragflow.RunFail {
+		t.Fatalf("ParseError = %+v", parseErr)
+	}
+	if len(docs) != 2 || docs[0].Run != ragflow.RunDone || docs[1].Run != ragflow.RunFail {
+		t.Fatalf("documents = %+v", docs)
+	}
+
+	done := make(map[string]bool)
+	for ev := range events {
+		if ev.Done {
+			done[ev.DocumentID] = true
+		}
+	}
+	if !done[ids[0]] || !done[ids[1]] {
+		t.Fatalf("final events for %v, want both documents", done)
+	}
+}
+
+func TestParseCancelled(t *testing.T) {
+	srv := ragflowtest.NewServer(ragflowtest.WithParseDuration(time.Minute))
+	defer srv.Close()
+	client := srv.NewClient()
+	datasetID, ids := uploadForParsing(t, client, "slow.txt")
+	ctx := context.Background()
+
+	if err := client.ParseDocuments(ctx, datasetID, ids); err != nil {
+		t.Fatalf("ParseDocuments: %v", err)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		client.StopParsingDocuments(ctx, datasetID, ids)
+	}()
+
+	docs, err := client.WaitForParsing(ctx, datasetID, ids, fastPolls)
+	if !errors.Is(err, ragflow.ErrParseCancelled) {
+		t.Fatalf("WaitForParsing error = %v, want ErrParseCancelled", err)
+	}
+	if len(docs) != 1 || docs[0].Run != ragflow.RunCancel {
+		t.Fatalf("documents = %+v", docs)
+	}
+}
+
+func TestParseStopOnCancel(t *testing.T) {
+	for _, stop := range []bool{false, true} {
+		srv := ragflowtest.NewServer(ragflowtest.WithParseDuration(time.Minute))
+		defer srv.Close()
+		client := srv.NewClient()
+		datasetID, ids := uploadForParsing(t, client, "slow.txt")
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+		_, err := client.ParseDocumentsAndWait(ctx, datasetID, ids, &ragflow.ParseWaitOptions{
+			PollInterval: 5 * time.Millisecond,
+			StopOnCancel: stop,
+		})
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("StopOnCancel %v: error = %v, want context.DeadlineExceeded", stop, err)
+		}
+
+		want := ragflow.RunRunning
+		if stop {
+			want = ragflow.RunCancel
+		}
+		if run := documentRun(t, client, datasetID, ids[0]); run != want {
+			t.Errorf("StopOnCancel %v: document run = %s, want %s", stop, run, want)
+		}
+	}
+}
+
+func TestWaitForParsingManyDocuments(t *testing.T) {
+	srv := ragflowtest.NewServer(ragflowtest.WithParseDuration(10 * time.Millisecond))
+	defer srv.Close()
+	client := srv.NewClient()
+
+	var names []string
+	for i := 0; i < 15; i++ {
+		names = append(names, fmt.Sprintf("doc-%02d.txt", i))
+	}
+	datasetID, ids := uploadForParsing(t, client, names...)
+
+	docs, err := client.ParseDocumentsAndWait(context.Background(), datasetID, ids, fastPolls)
+	if err != nil {
+		t.Fatalf("ParseDocumentsAndWait: %v", err)
+	}
+	if len(docs) != len(ids) {
+		t.Fatalf("got %d documents, want %d", len(docs), len(ids))
+	}
+	for i, doc := range docs {
+		if doc.ID != ids[i] || doc.Run != ragflow.RunDone {
+			t.Fatalf("document %d = %s in state %s", i, doc.ID, doc.Run)
+		}
+	}
+}
+
+func TestWaitForParsingMissingDocument(t *testing.T) {
+	srv := ragflowtest.NewServer(ragflowtest.WithParseDuration(10 * time.Millisecond))
+	defer srv.Close()
+	client := srv.NewClient()
+	datasetID, ids := uploadForParsing(t, client, "a.txt")
+	ctx := context.Background()
+
+	if err := client.ParseDocuments(ctx, datasetID, ids); err != nil {
+		t.Fatalf("ParseDocuments: %v", err)
+	}
+	docs, err := client.WaitForParsing(ctx, datasetID, append(ids, "missing"), fastPolls)
+	if !errors.Is(err, ragflow.ErrNotFound) {
+		t.Fatalf("WaitForParsing error = %v, want ErrNotFound", err)
+	}
+	if len(docs) != 1 || docs[0].Run != ragflow.RunDone {
+		t.Fatalf("documents = %+v", docs)
+	}
+}
@@ -0,0 +1,410 @@
+// Package cassette records HTTP traffic between the ragflow client and a
+// RAGFlow server to JSON files and replays it, so integration tests can run
+// deterministically without the server:
+//
+//	rec, err := cassette.New("testdata/datasets.json", cassette.ModeAuto)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer rec.Stop()
+//
+//	client := ragflow.NewClient(apiKey,
+//		ragflow.WithBaseURL(baseURL),
+//		ragflow.WithHTTPClient(rec.HTTPClient()),
+//	)
+//
+// Credentials are redacted before anything is written: the Authorization,
+// Cookie and Set-Cookie headers, and password, API key and token fields of
+// JSON bodies and streamed events, including the RSA-encrypted login password.
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/kevinroleke/ragflow-go/internal/redact"
+)
+
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette and fails requests that
+	// were not recorded.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the server and overwrites the cassette on
+	// Stop.
+	ModeRecord
+	// ModeAuto replays the cassette if the file exists and records it
+	// otherwise.
+	ModeAuto
+)
+
+// ErrNoInteraction is returned in replay mode for requests that have no
+// unused matching interaction in the cassette.
+var ErrNoInteraction = errors.New("no matching interaction in cassette")
+
+// Cassette is the file format of a recording.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+type Response struct {
+	StatusCode   int         `json:"status_code"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+	// Events holds the events of a server-sent event stream in place of Body.
+	Events []string `json:"events,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that records or replays a cassette.
+type Recorder struct {
+	path      string
+	recording bool
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+type Option func(*Recorder)
+
+// WithTransport sets the transport used to reach the server while recording.
+// It defaults to http.DefaultTransport.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = rt
+	}
+}
+
+// New returns a Recorder for the cassette at path. In replay mode the
+// cassette is loaded immediately.
+func New(path string, mode Mode, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		transport: http.DefaultTransport,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	switch mode {
+	case ModeRecord:
+		r.recording = true
+	case ModeAuto:
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			r.recording = true
+		}
+	}
+	if r.recording {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("error parsing cassette %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Recording reports whether the recorder sends requests to the server rather
+// than replaying them.
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+// HTTPClient returns an http.Client using the recorder as its transport.
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Stop writes the cassette when recording. Response bodies that were not
+// read to the end are saved as far as they were read.
+func (r *Recorder) Stop() error {
+	if !r.recording {
+		return nil
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(&r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("error marshaling cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("error creating cassette directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error writing cassette: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading request body: %w", err)
+		}
+	}
+
+	recorded := recordRequest(req, body)
+	if r.recording {
+		return r.record(req, body, recorded)
+	}
+	return r.replay(req, recorded)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte, recorded Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	if req.Body != nil {
+		out.Body = io.NopCloser(bytes.NewReader(body))
+		out.ContentLength = int64(len(body))
+		out.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Headers:    redactHeaders(resp.Header),
+		},
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+
+	contentType := resp.Header.Get("Content-Type")
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		done: func(data []byte) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if isEventStream(contentType) {
+				events := splitEvents(data)
+				for i, event := range events {
+					events[i] = redactEvent(event)
+				}
+				interaction.Response.Events = events
+				return
+			}
+			if isJSON(contentType) {
+				data = redact.JSON(data)
+			}
+			interaction.Response.Body, interaction.Response.BodyEncoding = encodeBody(data)
+		},
+	}
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !matches(interaction.Request, recorded) {
+			continue
+		}
+		r.used[i] = true
+
+		resp := &interaction.Response
+		var body []byte
+		if resp.Events != nil {
+			body = joinEvents(resp.Events)
+		} else {
+			var err error
+			body, err = decodeBody(resp.Body, resp.BodyEncoding)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding recorded response: %w", err)
+			}
+		}
+
+		header := resp.Headers.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		header.Del("Content-Length")
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+			StatusCode:    resp.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, &missError{method: recorded.Method, url: recorded.URL}
+}
+
+type missError struct {
+	method string
+	url    string
+}
+
+func (e *missError) Error() string {
+	return fmt.Sprintf("cassette: %s %s: %v", e.method, e.url, ErrNoInteraction)
+}
+
+func (e *missError) Unwrap() error {
+	return ErrNoInteraction
+}
+
+// Retryable keeps the client's retry policy from retrying a replay miss.
+func (e *missError) Retryable() bool {
+	return false
+}
+
+// recordingBody copies a response body as it is read and hands the copy to
+// done at EOF or on Close, whichever comes first.
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	done func([]byte)
+	once sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *recordingBody) finish() {
+	b.once.Do(func() {
+		b.done(b.buf.Bytes())
+	})
+}
+
+func recordRequest(req *http.Request, body []byte) Request {
+	recorded := Request{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Headers: redactHeaders(req.Header),
+	}
+
+	contentType := req.Header.Get("Content-Type")
+	switch {
+	case isJSON(contentType):
+		body = redact.JSON(body)
+	case strings.HasPrefix(contentType, "multipart/"):
+		body = normalizeMultipart(contentType, body)
+		if recorded.Headers != nil {
+			recorded.Headers.Set("Content-Type", string(normalizeMultipart(contentType, []byte(contentType))))
+		}
+	}
+	recorded.Body, recorded.BodyEncoding = encodeBody(body)
+	return recorded
+}
+
+func isJSON(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/json")
+}
+
+func isEventStream(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/event-stream")
+}
+
+// encodeBody stores text bodies as is and binary bodies as base64.
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func decodeBody(body, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(body), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(body)
+	default:
+		return nil, fmt.Errorf("unknown body encoding %q", encoding)
+	}
+}
+
+func splitEvents(data []byte) []string {
+	events := []string{}
+	for _, event := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n\n") {
+		if strings.TrimSpace(event) != "" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// redactEvent masks credentials in the JSON data lines of an event.
+func redactEvent(event string) string {
+	lines := strings.Split(event, "\n")
+	for i, line := range lines {
+		payload, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		payload = strings.TrimPrefix(payload, " ")
+		if masked := redact.JSON([]byte(payload)); string(masked) != payload {
+			lines[i] = "data: " + string(masked)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func joinEvents(events []string) []byte {
+	var buf bytes.Buffer
+	for _, event := range events {
+		buf.WriteString(event)
+		buf.WriteString("\n\n")
+	}
+	return buf.Bytes()
+}
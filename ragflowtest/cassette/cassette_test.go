@@ -0,0 +1,130 @@
+package cassette_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kevinroleke/ragflow-go/ragflowtest/cassette"
+)
+
+const secret = "ragflow-SECRETKEY"
+
+func TestRecordRedactsCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"code":0,"data":{"token":"`+secret+`","beta":"`+secret+`","docs":[{"name":"a.txt","token":42}],"access_token":"`+secret+`"}}`)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	body := `{"name":"key","api_key":"` + secret + `"}`
+
+	rec, err := cassette.New(path, cassette.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/tokens", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+secret)
+	resp, err := rec.HTTPClient().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if err := rec.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "SECRETKEY") {
+		t.Fatalf("cassette contains the secret:\n%s", data)
+	}
+
+	rec, err = cassette.New(path, cassette.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Stop()
+	req, _ = http.NewRequest(http.MethodPost, "http://replay.invalid/api/v1/tokens", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = rec.HTTPClient().Do(req)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var replayed struct {
+		Data struct {
+			Token string `json:"token"`
+			Docs  []struct {
+				Token int `json:"token"`
+			} `json:"docs"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&replayed); err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Data.Token != "[REDACTED]" {
+		t.Errorf("replayed token = %q", replayed.Data.Token)
+	}
+	if len(replayed.Data.Docs) != 1 || replayed.Data.Docs[0].Token != 42 {
+		t.Errorf("token count was not kept: %+v", replayed.Data.Docs)
+	}
+}
+
+func TestRecordRedactsEvents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data:{\"code\":0,\"data\":{\"answer\":\"hi\",\"access_token\":\""+secret+"\"}}\n\n")
+		io.WriteString(w, "data:[DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "stream.json")
+	rec, err := cassette.New(path, cassette.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rec.HTTPClient().Get(srv.URL + "/api/v1/chats/c1/completions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if err := rec.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "SECRETKEY") {
+		t.Fatalf("cassette contains the secret:\n%s", data)
+	}
+
+	rec, err = cassette.New(path, cassette.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Stop()
+	resp, err = rec.HTTPClient().Get("http://replay.invalid/api/v1/chats/c1/completions")
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `"answer":"hi"`) || !strings.Contains(string(body), "data:[DONE]") {
+		t.Fatalf("replayed stream = %q", body)
+	}
+}
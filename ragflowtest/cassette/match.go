@@ -0,0 +1,86 @@
+package cassette
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/kevinroleke/ragflow-go/internal/redact"
+)
+
+const redacted = redact.Mask
+
+// multipartBoundary replaces the random boundary of multipart bodies so that
+// uploads match across runs.
+const multipartBoundary = "cassette-boundary"
+
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "X-Api-Key"}
+
+// matches reports whether a request corresponds to a recorded one. Requests
+// match on method, path, query and normalized body; the host is ignored so a
+// cassette can be replayed against any base URL.
+func matches(recorded, req Request) bool {
+	if recorded.Method != req.Method || recorded.Body != req.Body {
+		return false
+	}
+
+	ru, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return false
+	}
+	return ru.Path == u.Path && ru.Query().Encode() == u.Query().Encode()
+}
+
+// redactHeaders copies h with credentials masked. Cookies keep their names so
+// that the client can still find the session cookie on replay.
+func redactHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	if out == nil {
+		return nil
+	}
+	for _, key := range sensitiveHeaders {
+		if out.Get(key) != "" {
+			out.Set(key, redacted)
+		}
+	}
+	for _, key := range []string{"Cookie", "Set-Cookie"} {
+		for i, v := range out.Values(key) {
+			out[http.CanonicalHeaderKey(key)][i] = redactCookies(v)
+		}
+	}
+	return out
+}
+
+func redactCookies(header string) string {
+	parts := strings.Split(header, ";")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+		name, _, ok := strings.Cut(parts[i], "=")
+		if ok && !isCookieAttribute(name) {
+			parts[i] = name + "=" + redacted
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+func isCookieAttribute(name string) bool {
+	switch strings.ToLower(name) {
+	case "path", "domain", "expires", "max-age", "samesite":
+		return true
+	}
+	return false
+}
+
+func normalizeMultipart(contentType string, body []byte) []byte {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["boundary"] == "" {
+		return body
+	}
+	return bytes.ReplaceAll(body, []byte(params["boundary"]), []byte(multipartBoundary))
+}
@@ -0,0 +1,895 @@
+package ragflowtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+)
+
+type assistant struct {
+	ragflow.Assistant
+	sessions map[string]*ragflow.Session
+}
+
+type agent struct {
+	ragflow.Agent
+	sessions map[string]*ragflow.AgentSession
+	files    map[string]*ragflow.AgentFile
+}
+
+func (s *Server) createAssistant(w http.ResponseWriter, r *http.Request, _ []string) {
+	var req ragflow.CreateAssistantRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, codeArgumentError, "`name` is required.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range s.assistants {
+		if a.Name == req.Name {
+			writeError(w, codeDataError, "Duplicated chat name in creating chat.")
+			return
+		}
+	}
+	for _, id := range req.DatasetIDs {
+		if _, ok := s.datasets[id]; !ok {
+			writeError(w, codeDataError, "You don't own the dataset "+id)
+			return
+		}
+	}
+
+	a := &assistant{
+		Assistant: ragflow.Assistant{
+			ID:          newID(),
+			Name:        req.Name,
+			Description: req.Description,
+			Avatar:      req.Avatar,
+			Language:    valueOr(req.Language, "English"),
+			Prompt: ragflow.Prompt{
+				Prompt:              req.Prompt,
+				EmptyResponse:       req.EmptyResponse,
+				Opener:              "Hi! I'm your assistant, what can I do for you?",
+				SimilarityThreshold: req.SimilarityThreshold,
+				TopN:                6,
+				ShowQuote:           true,
+			},
+			LLMSetting:             req.LLMSetting,
+			LLMModel:               req.LLMModel,
+			DatasetIDs:             req.DatasetIDs,
+			TopK:                   req.TopK,
+			SimilarityThreshold:    req.SimilarityThreshold,
+			VectorSimilarityWeight: req.VectorSimilarityWeight,
+			Temperature:            req.Temperature,
+			MaxTokens:              req.MaxTokens,
+			CreateTime:             now(),
+			UpdateTime:             now(),
+		},
+		sessions: make(map[string]*ragflow.Session),
+	}
+	s.assistants[a.ID] = a
+
+	writeData(w, a.Assistant)
+}
+
+func (s *Server) listAssistants(w http.ResponseWriter, r *http.Request, _ []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, id := r.URL.Query().Get("name"), r.URL.Query().Get("id")
+	var items []ragflow.Assistant
+	for _, a := range s.assistants {
+		if (name != "" && a.Name != name) || (id != "" && a.ID != id) {
+			continue
+		}
+		items = append(items, a.Assistant)
+	}
+
+	sortByCreateTime(items, func(a ragflow.Assistant) time.Time { return a.CreateTime.Time }, queryDesc(r))
+	writeData(w, map[string]interface{}{
+		"total": len(items),
+		"items": paginate(items, r),
+	})
+}
+
+func (s *Server) getAssistant(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.assistants[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "You don't own the chat")
+		return
+	}
+	writeData(w, a.Assistant)
+}
+
+func (s *Server) updateAssistant(w http.ResponseWriter, r *http.Request, params []string) {
+	var req ragflow.UpdateAssistantRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.assistants[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "You do not own the chat")
+		return
+	}
+
+	if req.Name != "" {
+		a.Name = req.Name
+	}
+	if req.Description != "" {
+		a.Description = req.Description
+	}
+	if req.Prompt != "" {
+		a.Prompt.Prompt = req.Prompt
+	}
+	if req.LLMModel != "" {
+		a.LLMModel = req.LLMModel
+	}
+	if req.DatasetIDs != nil {
+		a.DatasetIDs = req.DatasetIDs
+	}
+	if req.Temperature != 0 {
+		a.Temperature = req.Temperature
+	}
+	if req.MaxTokens != 0 {
+		a.MaxTokens = req.MaxTokens
+	}
+	a.UpdateTime = now()
+
+	writeData(w, a.Assistant)
+}
+
+func (s *Server) deleteAssistant(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.assistants[params[0]]; !ok {
+		writeError(w, codeDataError, "Assistant("+params[0]+") not found.")
+		return
+	}
+	delete(s.assistants, params[0])
+	writeOK(w)
+}
+
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request, params []string) {
+	var req ragflow.CreateSessionRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.assistants[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "You do not own the assistant.")
+		return
+	}
+
+	session := &ragflow.Session{
+		ID:   newID(),
+		Name: valueOr(req.Name, "New session"),
+		Messages: []ragflow.ChatMessage{
+			{Role: "assistant", Content: a.Prompt.Opener},
+		},
+		CreateTime: now(),
+		UpdateTime: now(),
+	}
+	a.sessions[session.ID] = session
+
+	writeData(w, session)
+}
+
+func (s *Server) listSessions(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.assistants[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "You don't own the assistant "+params[0]+".")
+		return
+	}
+
+	name, id := r.URL.Query().Get("name"), r.URL.Query().Get("id")
+	var items []ragflow.Session
+	for _, session := range a.sessions {
+		if (name != "" && session.Name != name) || (id != "" && session.ID != id) {
+			continue
+		}
+		items = append(items, *session)
+	}
+
+	sortByCreateTime(items, func(s ragflow.Session) time.Time { return s.CreateTime.Time }, queryDesc(r))
+	writeData(w, map[string]interface{}{
+		"total": len(items),
+		"items": paginate(items, r),
+	})
+}
+
+func (s *Server) getSession(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.session(params[0], params[1])
+	if !ok {
+		writeError(w, codeDataError, "Session does not exist")
+		return
+	}
+	writeData(w, session)
+}
+
+func (s *Server) updateSession(w http.ResponseWriter, r *http.Request, params []string) {
+	var req ragflow.UpdateSessionRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.session(params[0], params[1])
+	if !ok {
+		writeError(w, codeDataError, "Session does not exist")
+		return
+	}
+	if req.Name != "" {
+		session.Name = req.Name
+	}
+	session.UpdateTime = now()
+	writeData(w, session)
+}
+
+func (s *Server) deleteSession(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.assistants[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "You don't own the chat "+params[0])
+		return
+	}
+	if _, ok := a.sessions[params[1]]; !ok {
+		writeError(w, codeDataError, "The chat doesn't own the session "+params[1])
+		return
+	}
+	delete(a.sessions, params[1])
+	writeOK(w)
+}
+
+func (s *Server) session(assistantID, sessionID string) (*ragflow.Session, bool) {
+	a, ok := s.assistants[assistantID]
+	if !ok {
+		return nil, false
+	}
+	session, ok := a.sessions[sessionID]
+	return session, ok
+}
+
+func (s *Server) openAIChatCompletion(w http.ResponseWriter, r *http.Request, params []string) {
+	var req ragflow.ChatCompletionRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if len(req.Messages) == 0 {
+		writeError(w, codeArgumentError, "You have to provide messages.")
+		return
+	}
+	question := req.Messages[len(req.Messages)-1].Content
+
+	s.mu.Lock()
+	a, ok := s.assistants[params[0]]
+	var answer string
+	if ok {
+		answer = s.reply(question)
+		if session, ok := a.sessions[req.ConversationID]; ok {
+			session.Messages = append(session.Messages,
+				ragflow.ChatMessage{Role: "user", Content: question},
+				ragflow.ChatMessage{Role: "assistant", Content: answer},
+			)
+		}
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, codeDataError, "You don't own the chat "+params[0])
+		return
+	}
+
+	id := "chatcmpl-" + newID()
+	if !req.Stream {
+		writeJSON(w, completion(id, params[0], "chat.completion", ragflow.ChatCompletionChoice{
+			Message:      ragflow.ChatMessage{Role: "assistant", Content: answer},
+			FinishReason: "stop",
+		}, question, answer))
+		return
+	}
+
+	stream := newEventStream(w)
+	for _, piece := range splitAnswer(answer) {
+		stream.send(completion(id, params[0], "chat.completion.chunk", ragflow.ChatCompletionChoice{
+			Delta: ragflow.ChatMessage{Role: "assistant", Content: piece},
+		}, question, ""))
+	}
+	stream.send(completion(id, params[0], "chat.completion.chunk", ragflow.ChatCompletionChoice{
+		FinishReason: "stop",
+	}, question, answer))
+	stream.done()
+}
+
+// converse serves RAGFlow's native chat completions. The reference lists the
+// chunks of the assistant's datasets that match the question.
+func (s *Server) converse(w http.ResponseWriter, r *http.Request, params []string) {
+	var req ragflow.ConverseRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Question == "" {
+		writeError(w, codeArgumentError, "`question` is required.")
+		return
+	}
+
+	s.mu.Lock()
+	a, ok := s.assistants[params[0]]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, codeDataError, "You don't own the chat "+params[0])
+		return
+	}
+
+	session, ok := a.sessions[req.SessionID]
+	if req.SessionID == "" {
+		session = &ragflow.Session{
+			ID:         newID(),
+			Name:       "New session",
+			Messages:   []ragflow.ChatMessage{{Role: "assistant", Content: a.Prompt.Opener}},
+			CreateTime: now(),
+		}
+		a.sessions[session.ID] = session
+	} else if !ok {
+		s.mu.Unlock()
+		writeError(w, codeDataError, "Session does not exist")
+		return
+	}
+
+	answer := s.reply(req.Question)
+	session.Messages = append(session.Messages,
+		ragflow.ChatMessage{Role: "user", Content: req.Question},
+		ragflow.ChatMessage{Role: "assistant", Content: answer},
+	)
+	session.UpdateTime = now()
+
+	threshold := valueOr(a.SimilarityThreshold, 0.2)
+	chunks, docAggs, _ := s.search(a.DatasetIDs, nil, req.Question, threshold, false)
+	if topN := valueOr(a.Prompt.TopN, 6); len(chunks) > topN {
+		chunks = chunks[:topN]
+	}
+	s.mu.Unlock()
+
+	resp := ragflow.ConverseResponse{
+		ID:        newID(),
+		SessionID: session.ID,
+		Answer:    answer,
+		Reference: ragflow.ChatReference{Chunks: chunks, DocAggs: docAggs, Total: len(chunks)},
+		Prompt:    a.Prompt.Prompt,
+	}
+	if !req.Stream {
+		writeData(w, resp)
+		return
+	}
+
+	// Like RAGFlow, every event carries the answer so far; the reference
+	// arrives with the last one.
+	stream := newEventStream(w)
+	partial := ragflow.ConverseResponse{ID: resp.ID, SessionID: resp.SessionID}
+	for _, piece := range splitAnswer(answer) {
+		partial.Answer += piece
+		stream.send(envelope{Code: codeSuccess, Data: partial})
+	}
+	stream.send(envelope{Code: codeSuccess, Data: resp})
+	stream.send(envelope{Code: codeSuccess, Data: true})
+}
+
+func (s *Server) createAgent(w http.ResponseWriter, r *http.Request, _ []string) {
+	var req ragflow.CreateAgentRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, codeArgumentError, "`title` is required.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range s.agents {
+		if a.Name == req.Name {
+			writeError(w, codeDataError, "Agent with title "+req.Name+" already exists.")
+			return
+		}
+	}
+
+	a := &agent{
+		Agent: ragflow.Agent{
+			ID:          newID(),
+			Name:        req.Name,
+			Description: req.Description,
+			Avatar:      req.Avatar,
+			Language:    req.Language,
+			DSL:         req.DSL,
+			CreateTime:  now(),
+			UpdateTime:  now(),
+		},
+		sessions: make(map[string]*ragflow.AgentSession),
+		files:    make(map[string]*ragflow.AgentFile),
+	}
+	s.agents[a.ID] = a
+
+	writeData(w, a.Agent)
+}
+
+func (s *Server) listAgents(w http.ResponseWriter, r *http.Request, _ []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, id := r.URL.Query().Get("name"), r.URL.Query().Get("id")
+	var items []ragflow.Agent
+	for _, a := range s.agents {
+		if (name != "" && a.Name != name) || (id != "" && a.ID != id) {
+			continue
+		}
+		items = append(items, a.Agent)
+	}
+
+	sortByCreateTime(items, func(a ragflow.Agent) time.Time { return a.CreateTime.Time }, queryDesc(r))
+	writeData(w, map[string]interface{}{
+		"total": len(items),
+		"items": paginate(items, r),
+	})
+}
+
+func (s *Server) getAgent(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.agents[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "Agent not found.")
+		return
+	}
+	writeData(w, a.Agent)
+}
+
+func (s *Server) updateAgent(w http.ResponseWriter, r *http.Request, params []string) {
+	var req ragflow.UpdateAgentRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.agents[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "Only owner of canvas authorized for this operation.")
+		return
+	}
+	if req.Name != "" {
+		a.Name = req.Name
+	}
+	if req.Description != "" {
+		a.Description = req.Description
+	}
+	if req.Avatar != "" {
+		a.Avatar = req.Avatar
+	}
+	if req.DSL != nil {
+		a.DSL = req.DSL
+	}
+	a.UpdateTime = now()
+
+	writeData(w, a.Agent)
+}
+
+func (s *Server) deleteAgent(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.agents[params[0]]; !ok {
+		writeError(w, codeDataError, "Only owner of canvas authorized for this operation.")
+		return
+	}
+	delete(s.agents, params[0])
+	writeOK(w)
+}
+
+func (s *Server) createAgentSession(w http.ResponseWriter, r *http.Request, params []string) {
+	var inputs map[string]interface{}
+	if !decodeBody(w, r, &inputs) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.agents[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "You cannot access the agent "+params[0])
+		return
+	}
+
+	var messages []ragflow.ChatMessage
+	if begin := beginParams(a.DSL); begin != nil {
+		for _, q := range begin.Query {
+			if _, ok := inputs[q.Key]; !ok && !q.Optional {
+				writeError(w, codeDataError, "`"+q.Key+"` is required")
+				return
+			}
+		}
+		if begin.Prologue != "" {
+			messages = append(messages, ragflow.ChatMessage{Role: "assistant", Content: begin.Prologue})
+		}
+	}
+
+	session := &ragflow.AgentSession{
+		ID:         newID(),
+		AgentID:    a.ID,
+		UserID:     r.URL.Query().Get("user_id"),
+		Source:     "agent",
+		Messages:   messages,
+		DSL:        a.DSL,
+		CreateTime: now(),
+		UpdateTime: now(),
+	}
+	a.sessions[session.ID] = session
+
+	writeData(w, session)
+}
+
+// beginParams returns the params of the Begin component of dsl, if any.
+func beginParams(dsl *ragflow.DSL) *ragflow.BeginParams {
+	if dsl == nil {
+		return nil
+	}
+	for _, c := range dsl.Components {
+		if c == nil {
+			continue
+		}
+		switch p := c.Obj.Params.(type) {
+		case *ragflow.BeginParams:
+			return p
+		case ragflow.BeginParams:
+			return &p
+		}
+	}
+	return nil
+}
+
+func (s *Server) listAgentSessions(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.agents[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "You don't own the agent "+params[0])
+		return
+	}
+
+	query := r.URL.Query()
+	id, userID := query.Get("id"), query.Get("user_id")
+	withDSL := query.Get("dsl") != "false" && query.Get("dsl") != "False"
+	var items []ragflow.AgentSession
+	for _, session := range a.sessions {
+		if (id != "" && session.ID != id) || (userID != "" && session.UserID != userID) {
+			continue
+		}
+		item := *session
+		if !withDSL {
+			item.DSL = nil
+		}
+		items = append(items, item)
+	}
+
+	sortByCreateTime(items, func(s ragflow.AgentSession) time.Time { return s.CreateTime.Time }, queryDesc(r))
+	writeData(w, map[string]interface{}{
+		"total": len(items),
+		"items": paginate(items, r),
+	})
+}
+
+func (s *Server) deleteAgentSessions(w http.ResponseWriter, r *http.Request, params []string) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.agents[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "You cannot access the agent "+params[0])
+		return
+	}
+	for _, id := range req.IDs {
+		if _, ok := a.sessions[id]; !ok {
+			writeError(w, codeDataError, "The agent doesn't own the session "+id)
+			return
+		}
+	}
+	for _, id := range req.IDs {
+		delete(a.sessions, id)
+	}
+
+	writeOK(w)
+}
+
+func (s *Server) uploadAgentFile(w http.ResponseWriter, r *http.Request, params []string) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, codeArgumentError, "No file part!")
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, codeArgumentError, "error reading upload: "+err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.agents[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "You don't own the agent "+params[0])
+		return
+	}
+
+	f := &ragflow.AgentFile{
+		ID:        newID(),
+		Name:      header.Filename,
+		Size:      int64(len(content)),
+		Extension: strings.TrimPrefix(path.Ext(header.Filename), "."),
+		MimeType:  valueOr(header.Header.Get("Content-Type"), "application/octet-stream"),
+		CreatedBy: a.CreatedBy,
+	}
+	a.files[f.ID] = f
+
+	writeData(w, f)
+}
+
+func (s *Server) agentCompletion(w http.ResponseWriter, r *http.Request, params []string) {
+	var req struct {
+		ragflow.ChatCompletionRequest
+		UserID string                        `json:"user_id"`
+		Inputs map[string]ragflow.AgentInput `json:"inputs"`
+		Files  []ragflow.AgentFile           `json:"files"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	question := ""
+	if len(req.Messages) > 0 {
+		question = req.Messages[len(req.Messages)-1].Content
+	}
+
+	s.mu.Lock()
+	a, ok := s.agents[params[0]]
+	var answer, sessionID string
+	var path []pathNode
+	if ok {
+		for _, f := range req.Files {
+			if a.files[f.ID] == nil {
+				s.mu.Unlock()
+				writeError(w, codeDataError, "File not found: "+f.ID)
+				return
+			}
+		}
+		session, exists := a.sessions[req.ConversationID]
+		if !exists {
+			if begin := beginParams(a.DSL); begin != nil {
+				for _, q := range begin.Query {
+					if _, ok := req.Inputs[q.Key]; !ok && !q.Optional {
+						s.mu.Unlock()
+						writeError(w, codeDataError, "`"+q.Key+"` is required")
+						return
+					}
+				}
+			}
+			session = &ragflow.AgentSession{ID: valueOr(req.ConversationID, newID()), AgentID: a.ID, UserID: req.UserID, Source: "agent", CreateTime: now()}
+			a.sessions[session.ID] = session
+		}
+		answer = s.reply(question)
+		session.Messages = append(session.Messages,
+			ragflow.ChatMessage{Role: "user", Content: question},
+			ragflow.ChatMessage{Role: "assistant", Content: answer},
+		)
+		session.UpdateTime = now()
+		sessionID = session.ID
+		path = runPath(a.DSL)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, codeDataError, "You don't own the agent "+params[0])
+		return
+	}
+
+	id := "chatcmpl-" + newID()
+	if !req.Stream {
+		writeJSON(w, completion(id, params[0], "chat.completion", ragflow.ChatCompletionChoice{
+			Message:      ragflow.ChatMessage{Role: "assistant", Content: answer},
+			FinishReason: "stop",
+		}, question, answer))
+		return
+	}
+
+	// Like newer RAGFlow, the run is streamed as typed events: each component
+	// on the path starts and finishes, and the last one streams the answer.
+	stream := newEventStream(w)
+	messageID, taskID := newID(), newID()
+	send := func(event string, data interface{}) {
+		stream.send(map[string]interface{}{
+			"event":      event,
+			"message_id": messageID,
+			"task_id":    taskID,
+			"session_id": sessionID,
+			"created_at": time.Now().Unix(),
+			"data":       data,
+		})
+	}
+
+	started := time.Now()
+	inputs := map[string]interface{}{"query": question}
+	for i, node := range path {
+		nodeStarted := time.Now()
+		info := map[string]interface{}{
+			"component_id":   node.id,
+			"component_name": node.id,
+			"component_type": node.name,
+			"inputs":         inputs,
+		}
+		send("node_started", info)
+
+		outputs := map[string]interface{}{}
+		if i == len(path)-1 {
+			for _, piece := range splitAnswer(answer) {
+				send("message", map[string]interface{}{"content": piece})
+			}
+			send("message_end", map[string]interface{}{"reference": map[string]interface{}{}})
+			outputs["content"] = answer
+		}
+
+		info["outputs"] = outputs
+		info["error"] = nil
+		info["elapsed_time"] = time.Since(nodeStarted).Seconds()
+		send("node_finished", info)
+	}
+	if len(path) == 0 {
+		for _, piece := range splitAnswer(answer) {
+			send("message", map[string]interface{}{"content": piece})
+		}
+	}
+	send("workflow_finished", map[string]interface{}{
+		"inputs":       inputs,
+		"outputs":      map[string]interface{}{"content": answer},
+		"error":        nil,
+		"elapsed_time": time.Since(started).Seconds(),
+	})
+	stream.done()
+}
+
+type pathNode struct {
+	id, name string
+}
+
+// runPath follows the first downstream of each component from Begin until
+// a component repeats, which stands in for a run of the agent.
+func runPath(dsl *ragflow.DSL) []pathNode {
+	if dsl == nil {
+		return nil
+	}
+	id := ""
+	for cid, c := range dsl.Components {
+		if c != nil && c.Obj.ComponentName == "Begin" {
+			id = cid
+		}
+	}
+
+	var path []pathNode
+	seen := make(map[string]bool)
+	for id != "" && !seen[id] {
+		c := dsl.Components[id]
+		if c == nil {
+			break
+		}
+		seen[id] = true
+		path = append(path, pathNode{id: id, name: c.Obj.ComponentName})
+		id = ""
+		if len(c.Downstream) > 0 {
+			id = c.Downstream[0]
+		}
+	}
+	return path
+}
+
+func completion(id, model, object string, choice ragflow.ChatCompletionChoice, question, answer string) ragflow.ChatCompletionResponse {
+	resp := ragflow.ChatCompletionResponse{
+		ID:      id,
+		Object:  object,
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []ragflow.ChatCompletionChoice{choice},
+	}
+	if answer != "" {
+		prompt := len(strings.Fields(question))
+		completion := len(strings.Fields(answer))
+		resp.Usage = ragflow.ChatCompletionUsage{
+			PromptTokens:     prompt,
+			CompletionTokens: completion,
+			TotalTokens:      prompt + completion,
+		}
+	}
+	return resp
+}
+
+// splitAnswer breaks an answer into the word-sized pieces that are streamed.
+func splitAnswer(answer string) []string {
+	var pieces []string
+	for _, word := range strings.SplitAfter(answer, " ") {
+		if word != "" {
+			pieces = append(pieces, word)
+		}
+	}
+	return pieces
+}
+
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return &eventStream{w: w, flusher: flusher}
+}
+
+func (e *eventStream) send(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(e.w, "data: %s\n\n", data)
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+}
+
+func (e *eventStream) done() {
+	fmt.Fprint(e.w, "data: [DONE]\n\n")
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+}
@@ -0,0 +1,832 @@
+package ragflowtest
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+)
+
+type dataset struct {
+	ragflow.Dataset
+	documents map[string]*document
+}
+
+type document struct {
+	ragflow.Document
+	datasetID   string
+	content     []byte
+	parseStart  time.Time
+	parseFailed bool
+	chunks      []*ragflow.Chunk
+}
+
+func (s *Server) createDataset(w http.ResponseWriter, r *http.Request, _ []string) {
+	var req ragflow.CreateDatasetRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, codeArgumentError, "`name` is required.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ds := range s.datasets {
+		if strings.EqualFold(ds.Name, req.Name) {
+			writeError(w, codeDuplicatedName, "Dataset name '"+req.Name+"' already exists")
+			return
+		}
+	}
+
+	ds := &dataset{
+		Dataset: ragflow.Dataset{
+			ID:               newID(),
+			Name:             req.Name,
+			Description:      req.Description,
+			Language:         valueOr(req.Language, "English"),
+			Permission:       valueOr(req.Permission, "me"),
+			ParseMethod:      valueOr(req.ParseMethod, "naive"),
+			ParserConfig:     req.ParserConfig,
+			Avatar:           req.Avatar,
+			EmbeddingModel:   valueOr(req.EmbeddingModel, "BAAI/bge-large-zh-v1.5"),
+			VectorSimilarity: req.VectorSimilarity,
+			ChunkTokenNumber: req.ChunkTokenNumber,
+			CreateTime:       now(),
+			UpdateTime:       now(),
+		},
+		documents: make(map[string]*document),
+	}
+	s.datasets[ds.ID] = ds
+
+	writeData(w, ds.Dataset)
+}
+
+func (s *Server) listDatasets(w http.ResponseWriter, r *http.Request, _ []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, id := r.URL.Query().Get("name"), r.URL.Query().Get("id")
+	var items []ragflow.Dataset
+	for _, ds := range s.datasets {
+		if (name != "" && ds.Name != name) || (id != "" && ds.ID != id) {
+			continue
+		}
+		items = append(items, s.datasetView(ds))
+	}
+	if (name != "" || id != "") && len(items) == 0 {
+		writeError(w, codeDataError, "You don't own the dataset.")
+		return
+	}
+
+	sortByCreateTime(items, func(d ragflow.Dataset) time.Time { return d.CreateTime.Time }, queryDesc(r))
+	writeData(w, paginate(items, r))
+}
+
+func (s *Server) getDataset(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, ok := s.datasets[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "You don't own the dataset.")
+		return
+	}
+	writeData(w, s.datasetView(ds))
+}
+
+func (s *Server) updateDataset(w http.ResponseWriter, r *http.Request, params []string) {
+	var req ragflow.UpdateDatasetRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, ok := s.datasets[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "You don't own the dataset.")
+		return
+	}
+
+	if req.Name != "" {
+		ds.Name = req.Name
+	}
+	if req.Description != "" {
+		ds.Description = req.Description
+	}
+	if req.Language != "" {
+		ds.Language = req.Language
+	}
+	if req.Permission != "" {
+		ds.Permission = req.Permission
+	}
+	if req.ParseMethod != "" {
+		ds.ParseMethod = req.ParseMethod
+	}
+	if req.ParserConfig != nil {
+		ds.ParserConfig = req.ParserConfig
+	}
+	if req.Avatar != "" {
+		ds.Avatar = req.Avatar
+	}
+	if req.EmbeddingModel != "" {
+		ds.EmbeddingModel = req.EmbeddingModel
+	}
+	if req.VectorSimilarity != 0 {
+		ds.VectorSimilarity = req.VectorSimilarity
+	}
+	ds.UpdateTime = now()
+
+	writeData(w, s.datasetView(ds))
+}
+
+func (s *Server) deleteDataset(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.datasets[params[0]]; !ok {
+		writeError(w, codeDataError, "You don't own the dataset.")
+		return
+	}
+	delete(s.datasets, params[0])
+	writeOK(w)
+}
+
+func (s *Server) datasetView(ds *dataset) ragflow.Dataset {
+	view := ds.Dataset
+	view.DocumentCount = len(ds.documents)
+	view.ChunkCount = 0
+	for _, doc := range ds.documents {
+		s.advanceParsing(doc)
+		view.ChunkCount += len(doc.chunks)
+	}
+	return view
+}
+
+func (s *Server) uploadDocuments(w http.ResponseWriter, r *http.Request, params []string) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, codeArgumentError, "No file part!")
+		return
+	}
+
+	type upload struct {
+		name, contentType string
+		content           []byte
+	}
+	var uploads []upload
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, codeArgumentError, "error reading upload: "+err.Error())
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			writeError(w, codeArgumentError, "error reading upload: "+err.Error())
+			return
+		}
+		if part.FileName() == "" {
+			writeError(w, codeArgumentError, "No file selected!")
+			return
+		}
+		uploads = append(uploads, upload{part.FileName(), part.Header.Get("Content-Type"), content})
+	}
+	if len(uploads) == 0 {
+		writeError(w, codeArgumentError, "No file part!")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, ok := s.datasets[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "Can't find the dataset with ID "+params[0]+"!")
+		return
+	}
+
+	docs := make([]ragflow.Document, 0, len(uploads))
+	for _, u := range uploads {
+		doc := &document{
+			Document: ragflow.Document{
+				ID:          newID(),
+				Name:        uniqueName(ds, u.name),
+				Type:        fileType(u.name),
+				Size:        int64(len(u.content)),
+				Run:         ragflow.RunUnstart,
+				ChunkMethod: ds.ParseMethod,
+				Location:    u.name,
+				Source:      "local",
+				CreateTime:  now(),
+				UpdateTime:  now(),
+			},
+			datasetID: ds.ID,
+			content:   u.content,
+		}
+		ds.documents[doc.ID] = doc
+		docs = append(docs, doc.Document)
+	}
+
+	writeData(w, docs)
+}
+
+func (s *Server) listDocuments(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, ok := s.datasets[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "You don't own the dataset "+params[0]+". ")
+		return
+	}
+
+	q := r.URL.Query()
+	id, keywords := q.Get("id"), strings.ToLower(q.Get("keywords"))
+	var items []ragflow.Document
+	for _, doc := range ds.documents {
+		if id != "" && doc.ID != id {
+			continue
+		}
+		if keywords != "" && !strings.Contains(strings.ToLower(doc.Name), keywords) {
+			continue
+		}
+		s.advanceParsing(doc)
+		items = append(items, doc.Document)
+	}
+	if id != "" && len(items) == 0 {
+		writeError(w, codeDataError, "You don't own the document "+id+".")
+		return
+	}
+
+	sortByCreateTime(items, func(d ragflow.Document) time.Time { return d.CreateTime.Time }, queryDesc(r))
+	writeData(w, map[string]interface{}{
+		"total": len(items),
+		"docs":  paginate(items, r),
+	})
+}
+
+func (s *Server) deleteDocuments(w http.ResponseWriter, r *http.Request, params []string) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, ok := s.datasets[params[0]]
+	if !ok {
+		writeError(w, codeDataError, "You don't own the dataset "+params[0]+". ")
+		return
+	}
+	for _, id := range req.IDs {
+		if _, ok := ds.documents[id]; !ok {
+			writeError(w, codeDataError, "Document not found!")
+			return
+		}
+	}
+	for _, id := range req.IDs {
+		delete(ds.documents, id)
+	}
+	writeOK(w)
+}
+
+// downloadDocument serves the raw file, as RAGFlow does for GET on a document.
+func (s *Server) downloadDocument(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.document(params[0], params[1])
+	if !ok {
+		writeError(w, codeDataError, "The dataset not own the document "+params[1]+".")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+doc.Name+`"`)
+	w.Write(doc.content)
+}
+
+func (s *Server) updateDocument(w http.ResponseWriter, r *http.Request, params []string) {
+	var req ragflow.UpdateDocumentRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.document(params[0], params[1])
+	if !ok {
+		writeError(w, codeDataError, "The dataset doesn't own the document.")
+		return
+	}
+
+	if req.Name != "" {
+		if fileType(req.Name) != doc.Type {
+			writeError(w, codeArgumentError, "The extension of file can't be changed")
+			return
+		}
+		doc.Name = req.Name
+	}
+	if req.MetaFields != nil {
+		doc.MetaFields = req.MetaFields
+	}
+	if req.ChunkMethod != "" && req.ChunkMethod != doc.ChunkMethod || req.ParserConfig != nil {
+		if req.ChunkMethod != "" {
+			doc.ChunkMethod = req.ChunkMethod
+		}
+		if req.ParserConfig != nil {
+			doc.ParserConfig = req.ParserConfig
+		}
+		s.resetParsing(doc)
+	}
+	doc.UpdateTime = now()
+
+	writeData(w, doc.Document)
+}
+
+func (s *Server) parseDocuments(w http.ResponseWriter, r *http.Request, params []string) {
+	var req struct {
+		IDs []string `json:"document_ids"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if len(req.IDs) == 0 {
+		writeError(w, codeArgumentError, "`document_ids` is required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range req.IDs {
+		if _, ok := s.document(params[0], id); !ok {
+			writeError(w, codeDataError, "You don't own the document "+id+".")
+			return
+		}
+	}
+	for _, id := range req.IDs {
+		doc, _ := s.document(params[0], id)
+		s.resetParsing(doc)
+		doc.Run = ragflow.RunRunning
+		doc.parseStart = time.Now()
+		doc.parseFailed = s.failParse != nil && s.failParse(doc.Name)
+		s.advanceParsing(doc)
+	}
+	writeOK(w)
+}
+
+func (s *Server) stopParsing(w http.ResponseWriter, r *http.Request, params []string) {
+	var req struct {
+		IDs []string `json:"document_ids"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range req.IDs {
+		doc, ok := s.document(params[0], id)
+		if !ok {
+			writeError(w, codeDataError, "You don't own the document "+id+".")
+			return
+		}
+		s.advanceParsing(doc)
+		if doc.Run != ragflow.RunRunning {
+			writeError(w, codeDataError, "Can't stop parsing document with progress at 0 or 1")
+			return
+		}
+	}
+	for _, id := range req.IDs {
+		doc, _ := s.document(params[0], id)
+		doc.Run = ragflow.RunCancel
+		doc.ProgressMsg += "\nTask has been canceled."
+	}
+	writeOK(w)
+}
+
+func (s *Server) document(datasetID, documentID string) (*document, bool) {
+	ds, ok := s.datasets[datasetID]
+	if !ok {
+		return nil, false
+	}
+	doc, ok := ds.documents[documentID]
+	return doc, ok
+}
+
+func (s *Server) resetParsing(doc *document) {
+	doc.Run = ragflow.RunUnstart
+	doc.Progress = 0
+	doc.ProgressMsg = ""
+	doc.ChunkCount = 0
+	doc.chunks = nil
+}
+
+// advanceParsing moves a running document's progress forward according to
+// the time elapsed since parsing started, chunking it once it completes.
+func (s *Server) advanceParsing(doc *document) {
+	if doc.Run != ragflow.RunRunning {
+		return
+	}
+
+	progress := 1.0
+	if s.parseDuration > 0 {
+		progress = float64(time.Since(doc.parseStart)) / float64(s.parseDuration)
+	}
+
+	switch {
+	case doc.parseFailed && progress >= 0.5:
+		doc.Run = ragflow.RunFail
+		doc.Progress = -1
+		doc.ProgressMsg = "[ERROR]Simulated parsing failure."
+	case progress >= 1:
+		doc.chunks = chunkContent(doc)
+		doc.Run = ragflow.RunDone
+		doc.Progress = 1
+		doc.ChunkCount = len(doc.chunks)
+		doc.ProgressMsg = "Task done."
+	default:
+		doc.Progress = progress
+		doc.ProgressMsg = "Page(1~100000000): OCR started"
+	}
+}
+
+// chunkContent splits a document into one chunk per paragraph.
+func chunkContent(doc *document) []*ragflow.Chunk {
+	var chunks []*ragflow.Chunk
+	for _, paragraph := range strings.Split(string(doc.content), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		chunks = append(chunks, &ragflow.Chunk{
+			ID:           newID(),
+			Content:      paragraph,
+			DocumentID:   doc.ID,
+			DocumentName: doc.Name,
+			DatasetID:    doc.datasetID,
+			Available:    true,
+			CreateTime:   now(),
+		})
+	}
+	return chunks
+}
+
+func (s *Server) listChunks(w http.ResponseWriter, r *http.Request, params []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.document(params[0], params[1])
+	if !ok {
+		writeError(w, codeDataError, "You don't own the document "+params[1]+".")
+		return
+	}
+	s.advanceParsing(doc)
+
+	q := r.URL.Query()
+	id, keywords := q.Get("id"), strings.ToLower(q.Get("keywords"))
+	items := []ragflow.Chunk{}
+	for _, chunk := range doc.chunks {
+		if id != "" && chunk.ID != id {
+			continue
+		}
+		if keywords != "" && !strings.Contains(strings.ToLower(chunk.Content), keywords) {
+			continue
+		}
+		items = append(items, *chunk)
+	}
+	if id != "" && len(items) == 0 {
+		writeError(w, codeDataError, "Chunk not found")
+		return
+	}
+
+	writeData(w, map[string]interface{}{
+		"total":  len(items),
+		"chunks": paginate(items, r),
+		"doc":    doc.Document,
+	})
+}
+
+func (s *Server) addChunk(w http.ResponseWriter, r *http.Request, params []string) {
+	var req ragflow.AddChunkRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		writeError(w, codeArgumentError, "`content` is required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.document(params[0], params[1])
+	if !ok {
+		writeError(w, codeDataError, "You don't own the document "+params[1]+".")
+		return
+	}
+
+	chunk := &ragflow.Chunk{
+		ID:                newID(),
+		Content:           req.Content,
+		DocumentID:        doc.ID,
+		DocumentName:      doc.Name,
+		DatasetID:         doc.datasetID,
+		ImportantKeywords: req.ImportantKeywords,
+		Questions:         req.Questions,
+		Available:         true,
+		CreateTime:        now(),
+	}
+	doc.chunks = append(doc.chunks, chunk)
+	doc.ChunkCount = len(doc.chunks)
+
+	writeData(w, map[string]interface{}{"chunk": chunk})
+}
+
+func (s *Server) updateChunk(w http.ResponseWriter, r *http.Request, params []string) {
+	var req ragflow.UpdateChunkRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.document(params[0], params[1])
+	if !ok {
+		writeError(w, codeDataError, "You don't own the document "+params[1]+".")
+		return
+	}
+	for _, chunk := range doc.chunks {
+		if chunk.ID != params[2] {
+			continue
+		}
+		if req.Content != "" {
+			chunk.Content = req.Content
+		}
+		if req.ImportantKeywords != nil {
+			chunk.ImportantKeywords = *req.ImportantKeywords
+		}
+		if req.Questions != nil {
+			chunk.Questions = *req.Questions
+		}
+		if req.Available != nil {
+			chunk.Available = *req.Available
+		}
+		chunk.UpdateTime = now()
+		writeOK(w)
+		return
+	}
+	writeError(w, codeDataError, "Can't find this chunk "+params[2])
+}
+
+func (s *Server) deleteChunks(w http.ResponseWriter, r *http.Request, params []string) {
+	var req struct {
+		IDs []string `json:"chunk_ids"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.document(params[0], params[1])
+	if !ok {
+		writeError(w, codeDataError, "You don't own the document "+params[1]+".")
+		return
+	}
+
+	remove := make(map[string]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		remove[id] = true
+	}
+	kept := doc.chunks[:0]
+	for _, chunk := range doc.chunks {
+		if remove[chunk.ID] {
+			delete(remove, chunk.ID)
+			continue
+		}
+		kept = append(kept, chunk)
+	}
+	doc.chunks = kept
+	doc.ChunkCount = len(kept)
+
+	if len(remove) > 0 {
+		writeError(w, codeDataError, "rm_chunk deleted chunks fewer than requested")
+		return
+	}
+	writeOK(w)
+}
+
+// retrieve scores chunks by the share of question terms they contain.
+func (s *Server) retrieve(w http.ResponseWriter, r *http.Request, _ []string) {
+	var req ragflow.RetrievalRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Question == "" {
+		writeError(w, codeArgumentError, "`question` is required.")
+		return
+	}
+	if len(req.DatasetIDs) == 0 {
+		writeError(w, codeArgumentError, "`dataset_ids` is required.")
+		return
+	}
+	threshold := 0.2
+	if req.SimilarityThreshold != nil {
+		threshold = *req.SimilarityThreshold
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matches, docAggs, missing := s.search(req.DatasetIDs, req.DocumentIDs, req.Question, threshold, req.Highlight)
+	if missing != "" {
+		writeError(w, codeDataError, "You don't own the dataset "+missing+".")
+		return
+	}
+
+	page, size := req.Page, req.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 30
+	}
+	start := (page - 1) * size
+	if start > len(matches) {
+		start = len(matches)
+	}
+	end := start + size
+	if end > len(matches) {
+		end = len(matches)
+	}
+
+	writeData(w, ragflow.RetrievalResult{
+		Chunks:  matches[start:end],
+		DocAggs: docAggs,
+		Total:   len(matches),
+	})
+}
+
+// search scores the available chunks of the given datasets against question,
+// returning the matches best first and their per-document counts. It reports
+// the first dataset that does not exist. The caller must hold s.mu.
+func (s *Server) search(datasetIDs, documentIDs []string, question string, threshold float64, highlightMatches bool) ([]ragflow.RetrievalChunk, []ragflow.DocAgg, string) {
+	docFilter := make(map[string]bool, len(documentIDs))
+	for _, id := range documentIDs {
+		docFilter[id] = true
+	}
+	terms := strings.Fields(strings.ToLower(question))
+
+	var matches []ragflow.RetrievalChunk
+	aggs := make(map[string]*ragflow.DocAgg)
+	for _, dsID := range datasetIDs {
+		ds, ok := s.datasets[dsID]
+		if !ok {
+			return nil, nil, dsID
+		}
+		for _, doc := range ds.documents {
+			if len(docFilter) > 0 && !docFilter[doc.ID] {
+				continue
+			}
+			s.advanceParsing(doc)
+			for _, chunk := range doc.chunks {
+				if !chunk.Available {
+					continue
+				}
+				score, highlight := scoreChunk(chunk.Content, terms)
+				if score < threshold {
+					continue
+				}
+				rc := ragflow.RetrievalChunk{
+					Chunk:           *chunk,
+					DocumentKeyword: doc.Name,
+					Similarity:      score,
+					TermSimilarity:  score,
+				}
+				if highlightMatches {
+					rc.Highlight = highlight
+				}
+				matches = append(matches, rc)
+
+				agg, ok := aggs[doc.ID]
+				if !ok {
+					agg = &ragflow.DocAgg{DocumentID: doc.ID, DocumentName: doc.Name}
+					aggs[doc.ID] = agg
+				}
+				agg.Count++
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Similarity > matches[j].Similarity
+	})
+	docAggs := make([]ragflow.DocAgg, 0, len(aggs))
+	for _, agg := range aggs {
+		docAggs = append(docAggs, *agg)
+	}
+	sort.Slice(docAggs, func(i, j int) bool {
+		return docAggs[i].Count > docAggs[j].Count
+	})
+
+	return matches, docAggs, ""
+}
+
+func scoreChunk(content string, terms []string) (float64, string) {
+	if len(terms) == 0 {
+		return 0, content
+	}
+
+	lower := strings.ToLower(content)
+	highlight := content
+	hits := 0
+	for _, term := range terms {
+		if !strings.Contains(lower, term) {
+			continue
+		}
+		hits++
+		if i := strings.Index(strings.ToLower(highlight), term); i >= 0 {
+			highlight = highlight[:i] + "<em>" + highlight[i:i+len(term)] + "</em>" + highlight[i+len(term):]
+		}
+	}
+	return float64(hits) / float64(len(terms)), highlight
+}
+
+func uniqueName(ds *dataset, name string) string {
+	taken := make(map[string]bool, len(ds.documents))
+	for _, doc := range ds.documents {
+		taken[doc.Name] = true
+	}
+	if !taken[name] {
+		return name
+	}
+
+	base, ext := name, ""
+	if i := strings.LastIndex(name, "."); i > 0 {
+		base, ext = name[:i], name[i:]
+	}
+	for n := 1; ; n++ {
+		candidate := base + "(" + strconv.Itoa(n) + ")" + ext
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+func fileType(name string) string {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return "other"
+	}
+	switch strings.ToLower(name[i+1:]) {
+	case "pdf":
+		return "pdf"
+	case "doc", "docx", "txt", "md", "markdown", "html", "htm", "json", "csv", "xlsx", "xls", "ppt", "pptx", "eml":
+		return "doc"
+	case "jpg", "jpeg", "png", "gif", "bmp", "tif", "tiff", "webp":
+		return "visual"
+	case "mp3", "wav", "aac", "flac", "ogg":
+		return "aural"
+	}
+	return "other"
+}
+
+func sortByCreateTime[T any](items []T, created func(T) time.Time, desc bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if desc {
+			return created(items[i]).After(created(items[j]))
+		}
+		return created(items[i]).Before(created(items[j]))
+	})
+}
+
+func valueOr[T comparable](v, def T) T {
+	var zero T
+	if v == zero {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,386 @@
+// Package ragflowtest provides an in-memory fake of the RAGFlow HTTP API for
+// testing code built on the ragflow client without a running RAGFlow.
+//
+//	srv := ragflowtest.NewServer()
+//	defer srv.Close()
+//
+//	client := srv.NewClient()
+//	ds, err := client.CreateDataset(ctx, ragflow.CreateDatasetRequest{Name: "docs"})
+//
+// The fake serves the routes the client calls, returns RAGFlow's response
+// envelopes and error codes, and keeps all state in memory.
+package ragflowtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+)
+
+const (
+	DefaultAPIKey   = "ragflow-test-key"
+	DefaultEmail    = "test@example.com"
+	DefaultPassword = "password"
+
+	defaultParseDuration = time.Second
+)
+
+// RAGFlow response codes used by the fake.
+const (
+	codeSuccess        = 0
+	codeArgumentError  = 101
+	codeDataError      = 102
+	codeAuthError      = 109
+	codeUnauthorized   = 401
+	codeNotFound       = 404
+	codeDuplicatedName = 1001
+)
+
+type Server struct {
+	// URL is the base URL of the fake, suitable for ragflow.WithBaseURL.
+	URL      string
+	APIKey   string
+	Email    string
+	Password string
+
+	ts             *httptest.Server
+	loginKey       *rsa.PrivateKey
+	plainPasswords bool
+	parseDuration  time.Duration
+	reply          func(question string) string
+	failParse      func(name string) bool
+	routes         []route
+
+	mu         sync.Mutex
+	datasets   map[string]*dataset
+	assistants map[string]*assistant
+	agents     map[string]*agent
+	logins     map[string]string
+	myLLMs     ragflow.MyLLMsResponse
+	factories  []ragflow.Factory
+	apiKeys    map[string]string
+}
+
+type Option func(*Server)
+
+func WithAPIKey(apiKey string) Option {
+	return func(s *Server) {
+		s.APIKey = apiKey
+	}
+}
+
+// WithUser sets the credentials accepted by the userland login route.
+func WithUser(email, password string) Option {
+	return func(s *Server) {
+		s.Email = email
+		s.Password = password
+	}
+}
+
+// WithPlainPasswords makes the login route expect unencrypted passwords, as
+// an instance behind an encrypting proxy does.
+func WithPlainPasswords() Option {
+	return func(s *Server) {
+		s.plainPasswords = true
+	}
+}
+
+// WithParseDuration sets how long simulated document parsing takes from
+// start to completion.
+func WithParseDuration(d time.Duration) Option {
+	return func(s *Server) {
+		s.parseDuration = d
+	}
+}
+
+// WithReply sets the function producing chat and agent answers. The default
+// answer echoes the question.
+func WithReply(reply func(question string) string) Option {
+	return func(s *Server) {
+		s.reply = reply
+	}
+}
+
+// WithParseFailure makes parsing fail for documents whose name matches.
+func WithParseFailure(fail func(name string) bool) Option {
+	return func(s *Server) {
+		s.failParse = fail
+	}
+}
+
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		APIKey:        DefaultAPIKey,
+		loginKey:      loginKey(),
+		Email:         DefaultEmail,
+		Password:      DefaultPassword,
+		parseDuration: defaultParseDuration,
+		reply: func(question string) string {
+			return "You said: " + question
+		},
+		datasets:   make(map[string]*dataset),
+		assistants: make(map[string]*assistant),
+		agents:     make(map[string]*agent),
+		logins:     make(map[string]string),
+		myLLMs:     defaultMyLLMs(),
+		factories:  defaultFactories(),
+		apiKeys:    make(map[string]string),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.registerRoutes()
+	s.ts = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.ts.URL
+	return s
+}
+
+func (s *Server) Close() {
+	s.ts.Close()
+}
+
+// NewClient returns a client configured for the fake's URL, API key and
+// login public key. Further options are applied after those.
+func (s *Server) NewClient(opts ...ragflow.ClientOption) *ragflow.Client {
+	defaults := []ragflow.ClientOption{ragflow.WithBaseURL(s.URL)}
+	if s.plainPasswords {
+		defaults = append(defaults, ragflow.WithPlainPasswordLogin())
+	} else {
+		defaults = append(defaults, ragflow.WithLoginPublicKey(s.PublicKeyPEM()))
+	}
+	return ragflow.NewClient(s.APIKey, append(defaults, opts...)...)
+}
+
+type authMode int
+
+const (
+	authAPIKey authMode = iota
+	authSession
+	authNone
+)
+
+type route struct {
+	method  string
+	pattern []string
+	auth    authMode
+	handle  func(w http.ResponseWriter, r *http.Request, params []string)
+}
+
+// handle registers a route. Routes under /v1/ are the web UI's routes and
+// authenticate with the login session; the rest require the API key.
+func (s *Server) handle(method, pattern string, handle func(w http.ResponseWriter, r *http.Request, params []string)) {
+	auth := authAPIKey
+	if strings.HasPrefix(pattern, "/v1/") {
+		auth = authSession
+	}
+	s.handleWithAuth(method, pattern, auth, handle)
+}
+
+func (s *Server) handleWithAuth(method, pattern string, auth authMode, handle func(w http.ResponseWriter, r *http.Request, params []string)) {
+	s.routes = append(s.routes, route{
+		method:  method,
+		pattern: strings.Split(strings.Trim(pattern, "/"), "/"),
+		auth:    auth,
+		handle:  handle,
+	})
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	for _, rt := range s.routes {
+		if rt.method != r.Method {
+			continue
+		}
+		params, ok := match(rt.pattern, segments)
+		if !ok {
+			continue
+		}
+
+		switch rt.auth {
+		case authAPIKey:
+			if r.Header.Get("Authorization") != "Bearer "+s.APIKey {
+				writeError(w, codeAuthError, "Authentication error: API key is invalid!")
+				return
+			}
+		case authSession:
+			if !s.userAuthorized(r) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(envelope{Code: codeUnauthorized, Message: "<Unauthorized '401: Unauthorized'>"})
+				return
+			}
+		}
+
+		rt.handle(w, r, params)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(envelope{Code: codeNotFound, Message: "Not Found: " + r.URL.Path})
+}
+
+// match compares path segments against a pattern in which "*" matches any
+// single segment, returning the matched segments.
+func match(pattern, segments []string) ([]string, bool) {
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+
+	var params []string
+	for i, p := range pattern {
+		if p == "*" {
+			params = append(params, segments[i])
+			continue
+		}
+		if p != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+type envelope struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeData(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, envelope{Code: codeSuccess, Data: data})
+}
+
+func writeOK(w http.ResponseWriter) {
+	writeJSON(w, envelope{Code: codeSuccess})
+}
+
+// writeError reports an error the way RAGFlow does: HTTP 200 with a non-zero
+// code in the body.
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, envelope{Code: code, Message: message})
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, codeArgumentError, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func now() ragflow.UnixTime {
+	return ragflow.UnixTime{Time: time.Now()}
+}
+
+// paginate returns the requested page of items. A missing page or page size
+// selects the first page of 30 items, as RAGFlow does.
+func paginate[T any](items []T, r *http.Request) []T {
+	page := queryInt(r, "page", 1)
+	size := queryInt(r, "page_size", 30)
+	if page < 1 || size < 1 {
+		return nil
+	}
+
+	start := (page - 1) * size
+	if start >= len(items) {
+		return []T{}
+	}
+	end := start + size
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+func queryInt(r *http.Request, key string, def int) int {
+	n, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+func queryDesc(r *http.Request) bool {
+	v := r.URL.Query().Get("desc")
+	return v == "" || v == "true" || v == "True" || v == "1"
+}
+
+func (s *Server) registerRoutes() {
+	s.handle(http.MethodPost, "/api/v1/datasets", s.createDataset)
+	s.handle(http.MethodGet, "/api/v1/datasets", s.listDatasets)
+	s.handle(http.MethodGet, "/api/v1/datasets/*", s.getDataset)
+	s.handle(http.MethodPut, "/api/v1/datasets/*", s.updateDataset)
+	s.handle(http.MethodDelete, "/api/v1/datasets/*", s.deleteDataset)
+
+	s.handle(http.MethodPost, "/api/v1/datasets/*/documents", s.uploadDocuments)
+	s.handle(http.MethodGet, "/api/v1/datasets/*/documents", s.listDocuments)
+	s.handle(http.MethodDelete, "/api/v1/datasets/*/documents", s.deleteDocuments)
+	s.handle(http.MethodGet, "/api/v1/datasets/*/documents/*", s.downloadDocument)
+	s.handle(http.MethodPut, "/api/v1/datasets/*/documents/*", s.updateDocument)
+	s.handle(http.MethodPost, "/api/v1/datasets/*/chunks", s.parseDocuments)
+	s.handle(http.MethodDelete, "/api/v1/datasets/*/chunks", s.stopParsing)
+
+	s.handle(http.MethodGet, "/api/v1/datasets/*/documents/*/chunks", s.listChunks)
+	s.handle(http.MethodPost, "/api/v1/datasets/*/documents/*/chunks", s.addChunk)
+	s.handle(http.MethodDelete, "/api/v1/datasets/*/documents/*/chunks", s.deleteChunks)
+	s.handle(http.MethodPut, "/api/v1/datasets/*/documents/*/chunks/*", s.updateChunk)
+	s.handle(http.MethodPost, "/api/v1/retrieval", s.retrieve)
+
+	for _, prefix := range []string{"/api/v1/chats", "/api/v1/chat/assistants"} {
+		s.handle(http.MethodPost, prefix, s.createAssistant)
+		s.handle(http.MethodGet, prefix, s.listAssistants)
+		s.handle(http.MethodGet, prefix+"/*", s.getAssistant)
+		s.handle(http.MethodPut, prefix+"/*", s.updateAssistant)
+		s.handle(http.MethodDelete, prefix+"/*", s.deleteAssistant)
+		s.handle(http.MethodPost, prefix+"/*/sessions", s.createSession)
+		s.handle(http.MethodGet, prefix+"/*/sessions", s.listSessions)
+		s.handle(http.MethodGet, prefix+"/*/sessions/*", s.getSession)
+		s.handle(http.MethodPut, prefix+"/*/sessions/*", s.updateSession)
+		s.handle(http.MethodDelete, prefix+"/*/sessions/*", s.deleteSession)
+	}
+	s.handle(http.MethodPost, "/api/v1/chats/*/completions", s.converse)
+	s.handle(http.MethodPost, "/api/v1/chats_openai/*/chat/completions", s.openAIChatCompletion)
+
+	s.handle(http.MethodPost, "/api/v1/agents", s.createAgent)
+	s.handle(http.MethodGet, "/api/v1/agents", s.listAgents)
+	s.handle(http.MethodGet, "/api/v1/agents/*", s.getAgent)
+	s.handle(http.MethodPut, "/api/v1/agents/*", s.updateAgent)
+	s.handle(http.MethodDelete, "/api/v1/agents/*", s.deleteAgent)
+	s.handle(http.MethodPost, "/api/v1/agents/*/sessions", s.createAgentSession)
+	s.handle(http.MethodGet, "/api/v1/agents/*/sessions", s.listAgentSessions)
+	s.handle(http.MethodDelete, "/api/v1/agents/*/sessions", s.deleteAgentSessions)
+	s.handle(http.MethodPost, "/api/v1/agents/*/completions", s.agentCompletion)
+	s.handle(http.MethodPost, "/api/v1/agents/*/upload", s.uploadAgentFile)
+
+	s.handleWithAuth(http.MethodPost, "/v1/user/login", authNone, s.login)
+	s.handle(http.MethodGet, "/v1/user/logout", s.logout)
+	s.handle(http.MethodGet, "/v1/llm/my_llms", s.getMyLLMs)
+	s.handle(http.MethodGet, "/v1/llm/factories", s.getFactories)
+	s.handle(http.MethodPost, "/v1/llm/set_api_key", s.setAPIKey)
+	s.handle(http.MethodPost, "/v1/llm/add_llm", s.addLLM)
+}
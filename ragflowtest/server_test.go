@@ -0,0 +1,247 @@
+package ragflowtest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+	"github.com/kevinroleke/ragflow-go/ragflowtest"
+)
+
+func newTestClient(t *testing.T, opts ...ragflowtest.Option) (*ragflowtest.Server, *ragflow.Client) {
+	t.Helper()
+	srv := ragflowtest.NewServer(opts...)
+	t.Cleanup(srv.Close)
+	return srv, srv.NewClient()
+}
+
+func TestDatasetsAndDocuments(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t, ragflowtest.WithParseDuration(20*time.Millisecond))
+
+	ds, err := client.CreateDataset(ctx, ragflow.CreateDatasetRequest{Name: "handbook"})
+	if err != nil {
+		t.Fatalf("CreateDataset: %v", err)
+	}
+	if got, err := client.GetDataset(ctx, ds.ID); err != nil || got.Name != "handbook" {
+		t.Fatalf("GetDataset = %+v, %v", got, err)
+	}
+
+	content := "Passwords are reset from the account page."
+	doc, err := client.UploadDocumentFromBytes(ctx, ds.ID, "faq.txt", []byte(content))
+	if err != nil {
+		t.Fatalf("UploadDocumentFromBytes: %v", err)
+	}
+
+	docs, err := client.ListDocuments(ctx, ds.ID, nil)
+	if err != nil {
+		t.Fatalf("ListDocuments: %v", err)
+	}
+	if docs.Data.Total != 1 || docs.Data.Items[0].ID != doc.ID {
+		t.Fatalf("ListDocuments = %+v", docs.Data)
+	}
+
+	parsed, err := client.ParseDocumentsAndWait(ctx, ds.ID, []string{doc.ID}, &ragflow.ParseWaitOptions{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("ParseDocumentsAndWait: %v", err)
+	}
+	if len(parsed) != 1 || parsed[0].Run != ragflow.RunDone {
+		t.Fatalf("ParseDocumentsAndWait = %+v", parsed)
+	}
+
+	data, err := client.DownloadDocument(ctx, ds.ID, doc.ID)
+	if err != nil || string(data) != content {
+		t.Fatalf("DownloadDocument = %q, %v", data, err)
+	}
+
+	if err := client.DeleteDocuments(ctx, ds.ID, []string{doc.ID}); err != nil {
+		t.Fatalf("DeleteDocuments: %v", err)
+	}
+	if err := client.DeleteDataset(ctx, ds.ID); err != nil {
+		t.Fatalf("DeleteDataset: %v", err)
+	}
+}
+
+func TestChunksAndRetrieval(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t)
+
+	ds, err := client.CreateDataset(ctx, ragflow.CreateDatasetRequest{Name: "faq"})
+	if err != nil {
+		t.Fatalf("CreateDataset: %v", err)
+	}
+	doc, err := client.UploadDocumentFromBytes(ctx, ds.ID, "faq.txt", []byte("placeholder"))
+	if err != nil {
+		t.Fatalf("UploadDocumentFromBytes: %v", err)
+	}
+
+	chunk, err := client.AddChunk(ctx, ds.ID, doc.ID, ragflow.AddChunkRequest{Content: "Reset your password from the account page."})
+	if err != nil {
+		t.Fatalf("AddChunk: %v", err)
+	}
+	if err := client.UpdateChunk(ctx, ds.ID, doc.ID, chunk.ID, ragflow.UpdateChunkRequest{ImportantKeywords: &[]string{"password"}}); err != nil {
+		t.Fatalf("UpdateChunk: %v", err)
+	}
+
+	chunks, err := client.ListChunks(ctx, ds.ID, doc.ID, nil)
+	if err != nil {
+		t.Fatalf("ListChunks: %v", err)
+	}
+	if chunks.Data.Total != 1 || chunks.Data.Items[0].ID != chunk.ID || len(chunks.Data.Items[0].ImportantKeywords) != 1 {
+		t.Fatalf("ListChunks = %+v", chunks.Data)
+	}
+
+	if err := client.UpdateChunk(ctx, ds.ID, doc.ID, chunk.ID, ragflow.UpdateChunkRequest{ImportantKeywords: &[]string{}}); err != nil {
+		t.Fatalf("UpdateChunk: %v", err)
+	}
+	chunks, err = client.ListChunks(ctx, ds.ID, doc.ID, nil)
+	if err != nil || len(chunks.Data.Items[0].ImportantKeywords) != 0 {
+		t.Fatalf("ListChunks after clearing keywords = %+v, %v", chunks, err)
+	}
+
+	result, err := client.Retrieve(ctx, ragflow.RetrievalRequest{Question: "reset password", DatasetIDs: []string{ds.ID}})
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if len(result.Chunks) == 0 || result.Chunks[0].ID != chunk.ID {
+		t.Fatalf("Retrieve = %+v", result)
+	}
+
+	if err := client.DeleteChunks(ctx, ds.ID, doc.ID, []string{chunk.ID}); err != nil {
+		t.Fatalf("DeleteChunks: %v", err)
+	}
+}
+
+func TestChats(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t, ragflowtest.WithReply(strings.ToUpper))
+
+	assistant, err := client.CreateAssistant(ctx, ragflow.CreateAssistantRequest{Name: "support"})
+	if err != nil {
+		t.Fatalf("CreateAssistant: %v", err)
+	}
+	session, err := client.CreateSession(ctx, assistant.ID, ragflow.CreateSessionRequest{Name: "s"})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	resp, err := client.Converse(ctx, assistant.ID, ragflow.ConverseRequest{Question: "hello", SessionID: session.ID})
+	if err != nil || resp.Answer != "HELLO" {
+		t.Fatalf("Converse = %+v, %v", resp, err)
+	}
+
+	respChan, errChan := client.ConverseStream(ctx, assistant.ID, ragflow.ConverseRequest{Question: "hello again", SessionID: session.ID})
+	var answer string
+	for resp := range respChan {
+		answer = resp.Answer
+	}
+	if err := <-errChan; err != nil || answer != "HELLO AGAIN" {
+		t.Fatalf("ConverseStream = %q, %v", answer, err)
+	}
+
+	completion, err := client.CreateChatCompletion(ctx, ragflow.ChatCompletionRequest{
+		Model:    assistant.ID,
+		Messages: []ragflow.ChatMessage{{Role: "user", Content: "hi"}},
+	})
+	if err != nil || completion.Choices[0].Message.Content != "HI" {
+		t.Fatalf("CreateChatCompletion = %+v, %v", completion, err)
+	}
+
+	if err := client.DeleteAssistant(ctx, assistant.ID); err != nil {
+		t.Fatalf("DeleteAssistant: %v", err)
+	}
+}
+
+func TestAgents(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t)
+
+	dsl, err := ragflow.NewDSLBuilder().
+		Component("begin", ragflow.BeginParams{Query: []ragflow.BeginQuery{{Key: "language", Type: "line"}}}).
+		Component("Answer:Talk", ragflow.AnswerParams{}).
+		Component("Generate:Reply", ragflow.GenerateParams{LLMID: "deepseek-chat"}).
+		Chain("begin", "Answer:Talk", "Generate:Reply", "Answer:Talk").
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if _, err := client.CreateAgent(ctx, ragflow.CreateAgentRequest{Name: "helper", DSL: dsl}); err != nil {
+		t.Fatalf("CreateAgent: %v", err)
+	}
+	agents, err := client.ListAgents(ctx, nil)
+	if err != nil || len(agents.Data.Items) != 1 {
+		t.Fatalf("ListAgents = %+v, %v", agents, err)
+	}
+	agent := agents.Data.Items[0]
+	if agent.DSL == nil || agent.DSL.Components["Generate:Reply"] == nil {
+		t.Fatalf("agent DSL = %+v", agent.DSL)
+	}
+
+	inputs := map[string]ragflow.AgentInput{"language": ragflow.TextInput("English")}
+	if _, err := client.RunAgentWith(ctx, agent.ID, ragflow.RunAgentRequest{Question: "hi"}); err == nil {
+		t.Fatal("RunAgentWith without the required input succeeded")
+	}
+	resp, err := client.RunAgentWith(ctx, agent.ID, ragflow.RunAgentRequest{Question: "hi", Inputs: inputs})
+	if err != nil || resp.Choices[0].Message.Content != "You said: hi" {
+		t.Fatalf("RunAgentWith = %+v, %v", resp, err)
+	}
+
+	events, errChan := client.RunAgentStreamWith(ctx, agent.ID, ragflow.RunAgentRequest{Question: "stream", Inputs: inputs})
+	var finished []string
+	var answer string
+	for event := range events {
+		switch e := event.(type) {
+		case *ragflow.AgentMessageEvent:
+			answer += e.Content
+		case *ragflow.AgentNodeFinishedEvent:
+			finished = append(finished, e.ComponentID)
+		}
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("RunAgentStreamWith: %v", err)
+	}
+	if answer != "You said: stream" || strings.Join(finished, ",") != "begin,Answer:Talk,Generate:Reply" {
+		t.Fatalf("RunAgentStreamWith answer %q, finished %v", answer, finished)
+	}
+
+	session, err := client.CreateAgentSession(ctx, agent.ID, &ragflow.CreateAgentSessionRequest{UserID: "u1", Inputs: inputs})
+	if err != nil {
+		t.Fatalf("CreateAgentSession: %v", err)
+	}
+	sessions, err := client.ListAgentSessions(ctx, agent.ID, &ragflow.ListAgentSessionsOptions{UserID: "u1"})
+	if err != nil || len(sessions.Data.Items) != 1 || sessions.Data.Items[0].ID != session.ID {
+		t.Fatalf("ListAgentSessions = %+v, %v", sessions, err)
+	}
+	if err := client.DeleteAgentSessions(ctx, agent.ID, []string{session.ID}); err != nil {
+		t.Fatalf("DeleteAgentSessions: %v", err)
+	}
+}
+
+func TestUserlandLogin(t *testing.T) {
+	ctx := context.Background()
+	srv := ragflowtest.NewServer()
+	t.Cleanup(srv.Close)
+	client := srv.NewClient(ragflow.WithUserPass(ragflowtest.DefaultEmail, ragflowtest.DefaultPassword))
+
+	llms, err := client.GetMyLLMs(ctx)
+	if err != nil {
+		t.Fatalf("GetMyLLMs: %v", err)
+	}
+	if len(llms) == 0 {
+		t.Fatal("GetMyLLMs returned no providers")
+	}
+	if session, err := client.Session(); err != nil || session == nil || session.Auth == "" {
+		t.Fatalf("Session = %+v, %v", session, err)
+	}
+
+	if err := client.Logout(ctx); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+
+	wrong := srv.NewClient(ragflow.WithUserPass(ragflowtest.DefaultEmail, "wrong"))
+	if _, err := wrong.GetMyLLMs(ctx); err == nil {
+		t.Fatal("GetMyLLMs with a wrong password succeeded")
+	}
+}
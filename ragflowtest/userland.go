@@ -0,0 +1,205 @@
+package ragflowtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"strings"
+	"sync"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+)
+
+var (
+	loginKeyOnce sync.Once
+	sharedKey    *rsa.PrivateKey
+)
+
+// loginKey returns the keypair standing in for RAGFlow's frontend key. It is
+// generated once per process since key generation is slow.
+func loginKey() *rsa.PrivateKey {
+	loginKeyOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(err)
+		}
+		sharedKey = key
+	})
+	return sharedKey
+}
+
+// PublicKeyPEM returns the public key the fake's login route expects
+// passwords to be encrypted with, for ragflow.WithLoginPublicKey.
+func (s *Server) PublicKeyPEM() string {
+	der, err := x509.MarshalPKIXPublicKey(&s.loginKey.PublicKey)
+	if err != nil {
+		panic(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// decryptPassword reverses the frontend's encryption: base64, RSA PKCS #1
+// v1.5, then base64 again around the password itself.
+func (s *Server) decryptPassword(password string) (string, bool) {
+	if s.plainPasswords {
+		return password, true
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(password)
+	if err != nil {
+		return "", false
+	}
+	encoded, err := rsa.DecryptPKCS1v15(nil, s.loginKey, encrypted)
+	if err != nil {
+		return "", false
+	}
+	plain, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return "", false
+	}
+	return string(plain), true
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request, _ []string) {
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	password, ok := s.decryptPassword(req.Password)
+	if !ok {
+		writeError(w, codeDataError, "Fail to crypt password")
+		return
+	}
+	if req.Email != s.Email || password != s.Password {
+		writeError(w, codeAuthError, "Email and password do not match!")
+		return
+	}
+
+	token, cookie := newID(), newID()
+	s.mu.Lock()
+	s.logins[cookie] = token
+	s.mu.Unlock()
+
+	w.Header().Set("Authorization", token)
+	http.SetCookie(w, &http.Cookie{Name: "session", Value: cookie, Path: "/", HttpOnly: true})
+	writeJSON(w, envelope{
+		Code:    codeSuccess,
+		Message: "Welcome back!",
+		Data: map[string]interface{}{
+			"email":        req.Email,
+			"nickname":     strings.Split(req.Email, "@")[0],
+			"access_token": token,
+		},
+	})
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request, _ []string) {
+	cookie, _ := r.Cookie("session")
+	s.mu.Lock()
+	delete(s.logins, cookie.Value)
+	s.mu.Unlock()
+	writeData(w, true)
+}
+
+// ExpireSessions invalidates every login session, as a server restart or
+// session timeout would.
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logins = make(map[string]string)
+}
+
+func (s *Server) userAuthorized(r *http.Request) bool {
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.logins[cookie.Value]
+	return ok && r.Header.Get("Authorization") == token
+}
+
+func (s *Server) getMyLLMs(w http.ResponseWriter, r *http.Request, _ []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeData(w, s.myLLMs)
+}
+
+func (s *Server) getFactories(w http.ResponseWriter, r *http.Request, _ []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeData(w, s.factories)
+}
+
+func (s *Server) setAPIKey(w http.ResponseWriter, r *http.Request, _ []string) {
+	var req ragflow.SetAPIKeyRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.hasFactory(req.FactoryName) {
+		writeJSON(w, envelope{Code: codeDataError, Message: "Unknown factory " + req.FactoryName, Data: false})
+		return
+	}
+	s.apiKeys[req.FactoryName] = req.ApiKey
+	writeData(w, true)
+}
+
+func (s *Server) addLLM(w http.ResponseWriter, r *http.Request, _ []string) {
+	var req ragflow.AddLLMRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.hasFactory(req.FactoryName) {
+		writeJSON(w, envelope{Code: codeDataError, Message: "Unknown factory " + req.FactoryName, Data: false})
+		return
+	}
+	provider := s.myLLMs[req.FactoryName]
+	provider.LLMs = append(provider.LLMs, ragflow.LLMModel{Name: req.ModelName, Type: req.ModelType})
+	s.myLLMs[req.FactoryName] = provider
+	writeData(w, true)
+}
+
+func (s *Server) hasFactory(name string) bool {
+	for _, f := range s.factories {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultMyLLMs() ragflow.MyLLMsResponse {
+	return ragflow.MyLLMsResponse{
+		"OpenAI": {
+			Tags: "LLM,TEXT EMBEDDING,TTS,TEXT RE-RANK,SPEECH2TEXT,MODERATION",
+			LLMs: []ragflow.LLMModel{
+				{Name: "gpt-4o", Type: "chat"},
+				{Name: "text-embedding-3-small", Type: "embedding"},
+			},
+		},
+	}
+}
+
+func defaultFactories() []ragflow.Factory {
+	return []ragflow.Factory{
+		{Name: "OpenAI", Status: "1", Tags: "LLM,TEXT EMBEDDING,TTS,TEXT RE-RANK,SPEECH2TEXT,MODERATION", ModelTypes: []string{"chat", "embedding", "tts", "speech2text"}},
+		{Name: "Ollama", Status: "1", Tags: "LLM,TEXT EMBEDDING,SPEECH2TEXT,MODERATION", ModelTypes: []string{"chat", "embedding", "image2text"}},
+		{Name: "Azure-OpenAI", Status: "1", Tags: "LLM,TEXT EMBEDDING,SPEECH2TEXT,MODERATION", ModelTypes: []string{"chat", "embedding"}},
+	}
+}
@@ -0,0 +1,20 @@
+package ragflow
+
+import (
+	"context"
+	"net/http"
+)
+
+func (c *Client) Retrieve(ctx context.Context, req RetrievalRequest) (*RetrievalResult, error) {
+	httpReq, err := c.newRequest(ctx, http.MethodPost, "/api/v1/retrieval", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp Response[RetrievalResult]
+	if err := c.do(httpReq, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp.Data, nil
+}
@@ -0,0 +1,20 @@
+package ragflow_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+)
+
+func TestRetrievalChunkDatasetID(t *testing.T) {
+	for _, body := range []string{`{"id":"c1","kb_id":"ds1"}`, `{"id":"c1","dataset_id":"ds1"}`} {
+		var chunk ragflow.RetrievalChunk
+		if err := json.Unmarshal([]byte(body), &chunk); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", body, err)
+		}
+		if chunk.ID != "c1" || chunk.DatasetID != "ds1" || chunk.Chunk.DatasetID != "ds1" {
+			t.Errorf("Unmarshal(%s) = %+v", body, chunk)
+		}
+	}
+}
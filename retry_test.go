@@ -0,0 +1,133 @@
+package ragflow
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestBackoffCap(t *testing.T) {
+	p := &RetryPolicy{MinBackoff: 10 * time.Millisecond, MaxBackoff: 80 * time.Millisecond}
+	for attempt := 1; attempt <= 10; attempt++ {
+		want := 10 * time.Millisecond << (attempt - 1)
+		if want > p.MaxBackoff {
+			want = p.MaxBackoff
+		}
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(attempt, errors.New("boom")); d < want/2 || d > want {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", attempt, d, want/2, want)
+			}
+		}
+	}
+}
+
+func TestBackoffRetryAfter(t *testing.T) {
+	p := &RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		retryAfter time.Duration
+		want       time.Duration
+	}{
+		{200 * time.Millisecond, 200 * time.Millisecond},
+		{time.Second, time.Second},
+		{time.Hour, time.Second},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("wrapped: %w", &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: tt.retryAfter})
+		if d := p.backoff(1, err); d != tt.want {
+			t.Errorf("backoff with Retry-After %v = %v, want %v", tt.retryAfter, d, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("3"); d != 3*time.Second {
+		t.Errorf("parseRetryAfter(3) = %v", d)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d := parseRetryAfter(date); d <= 50*time.Second || d > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %v", date, d)
+	}
+	for _, value := range []string{"", "0", "-1", "soon", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)} {
+		if d := parseRetryAfter(value); d != 0 {
+			t.Errorf("parseRetryAfter(%q) = %v, want 0", value, d)
+		}
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	urlErr := func(err error) error {
+		return fmt.Errorf("error making request: %w", &url.Error{Op: "Get", URL: "http://ragflow.invalid", Err: err})
+	}
+	dial := func(errno syscall.Errno) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"timeout", urlErr(timeoutError{}), true},
+		{"connection refused", urlErr(dial(syscall.ECONNREFUSED)), true},
+		{"connection reset", urlErr(dial(syscall.ECONNRESET)), true},
+		{"unexpected EOF", urlErr(io.ErrUnexpectedEOF), true},
+		{"unsupported scheme", urlErr(errors.New(`unsupported protocol scheme "ftp"`)), false},
+		{"TLS verification", urlErr(x509.UnknownAuthorityError{}), false},
+		{"canceled", urlErr(context.Canceled), false},
+		{"deadline", context.DeadlineExceeded, false},
+		{"rate limited", &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"unavailable", &APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"bad request", &APIError{StatusCode: http.StatusBadRequest}, false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("%s: IsRetryable(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryAfterIsCapped(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(w, `{"code":429,"message":"slow down"}`)
+			return
+		}
+		io.WriteString(w, `{"code":0,"data":[]}`)
+	}))
+	defer srv.Close()
+
+	client := NewClient("key",
+		WithBaseURL(srv.URL),
+		WithRetryPolicy(&RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: 20 * time.Millisecond}),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.ListDatasets(ctx, nil); err != nil {
+		t.Fatalf("ListDatasets: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("server saw %d requests, want 2", n)
+	}
+}
@@ -0,0 +1,299 @@
+package ragflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// LoginSession is a userland login: the Authorization token and session
+// cookie returned by /v1/user/login. The /v1/ endpoints require it instead of
+// the API key.
+type LoginSession struct {
+	Email  string `json:"email"`
+	Auth   string `json:"auth"`
+	Cookie string `json:"cookie"`
+}
+
+// SessionStore keeps the login session between requests. Implementations
+// must be safe for concurrent use. LoadSession returns nil and no error when
+// no session is stored.
+type SessionStore interface {
+	LoadSession() (*LoginSession, error)
+	SaveSession(session *LoginSession) error
+	DeleteSession() error
+}
+
+type memorySessionStore struct {
+	mu      sync.Mutex
+	session *LoginSession
+}
+
+func (s *memorySessionStore) LoadSession() (*LoginSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.session, nil
+}
+
+func (s *memorySessionStore) SaveSession(session *LoginSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session = session
+	return nil
+}
+
+func (s *memorySessionStore) DeleteSession() error {
+	return s.SaveSession(nil)
+}
+
+// FileSessionStore persists the login session as JSON in a file, so that a
+// later run can reuse it instead of logging in again.
+type FileSessionStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func NewFileSessionStore(path string) *FileSessionStore {
+	return &FileSessionStore{Path: path}
+}
+
+func (s *FileSessionStore) LoadSession() (*LoginSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading session file: %w", err)
+	}
+
+	var session LoginSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("error decoding session file: %w", err)
+	}
+	return &session, nil
+}
+
+func (s *FileSessionStore) SaveSession(session *LoginSession) error {
+	if session == nil {
+		return s.DeleteSession()
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("error encoding session: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return fmt.Errorf("error creating session directory: %w", err)
+	}
+	if err := os.WriteFile(s.Path, data, 0o600); err != nil {
+		return fmt.Errorf("error writing session file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileSessionStore) DeleteSession() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error removing session file: %w", err)
+	}
+	return nil
+}
+
+// WithCredentials sets the email and password used for the userland /v1/
+// endpoints. The client logs in on the first such request and again whenever
+// the session has expired.
+func WithCredentials(email, password string) ClientOption {
+	return func(c *Client) {
+		c.Username = email
+		c.Password = password
+	}
+}
+
+// WithSessionStore keeps the login session in store instead of in memory.
+// A session restored from the store is used without logging in.
+func WithSessionStore(store SessionStore) ClientOption {
+	return func(c *Client) {
+		c.sessions = store
+	}
+}
+
+// Session returns the current login session, or nil if the client has not
+// logged in.
+func (c *Client) Session() (*LoginSession, error) {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	return c.loadSession()
+}
+
+// SetSession replaces the login session, for example with one saved by an
+// earlier run.
+func (c *Client) SetSession(session *LoginSession) error {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	return c.saveSession(session)
+}
+
+// Logout ends the login session on the server and removes it from the
+// session store.
+func (c *Client) Logout(ctx context.Context) error {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	session, err := c.loadSession()
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return nil
+	}
+
+	req, err := c.newUserRequest(ctx, http.MethodGet, "/v1/user/logout", nil)
+	if err != nil {
+		return err
+	}
+	setSessionHeaders(req, session)
+
+	// An expired session is as good as logged out.
+	if err := c.do(req, &Response[interface{}]{}); err != nil && !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+	if err := c.sessions.DeleteSession(); err != nil {
+		return err
+	}
+	c.setSessionFields(nil)
+	return nil
+}
+
+// userSession returns the stored login session, logging in if there is none.
+func (c *Client) userSession(ctx context.Context) (*LoginSession, error) {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	session, err := c.loadSession()
+	if err != nil {
+		return nil, err
+	}
+	if session != nil && (session.Email == "" || c.Username == "" || session.Email == c.Username) {
+		return session, nil
+	}
+	return c.login(ctx)
+}
+
+// renewSession logs in again after expired was rejected. Concurrent requests
+// that fail with the same session share one login.
+func (c *Client) renewSession(ctx context.Context, expired *LoginSession) (*LoginSession, error) {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	session, err := c.loadSession()
+	if err != nil {
+		return nil, err
+	}
+	if session != nil && *session != *expired {
+		return session, nil
+	}
+	return c.login(ctx)
+}
+
+// login logs in with the client's credentials and stores the session. The
+// caller must hold loginMu.
+func (c *Client) login(ctx context.Context) (*LoginSession, error) {
+	if c.Username == "" || c.Password == "" {
+		return nil, fmt.Errorf("error logging in: no credentials configured: %w", ErrUnauthorized)
+	}
+
+	auth, cookie, err := c.postLogin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &LoginSession{Email: c.Username, Auth: auth, Cookie: cookie}
+	if err := c.saveSession(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// loadSession returns the stored session, adopting one set through the
+// deprecated SessionAuth and SessionCookie fields if the store holds none.
+// The caller must hold loginMu.
+func (c *Client) loadSession() (*LoginSession, error) {
+	session, err := c.sessions.LoadSession()
+	if err != nil {
+		return nil, err
+	}
+	if session == nil && c.SessionAuth != "" {
+		session = &LoginSession{Auth: c.SessionAuth, Cookie: c.SessionCookie}
+		if err := c.sessions.SaveSession(session); err != nil {
+			return nil, err
+		}
+	}
+	c.setSessionFields(session)
+	return session, nil
+}
+
+// saveSession stores session. The caller must hold loginMu.
+func (c *Client) saveSession(session *LoginSession) error {
+	if err := c.sessions.SaveSession(session); err != nil {
+		return err
+	}
+	c.setSessionFields(session)
+	return nil
+}
+
+func (c *Client) setSessionFields(session *LoginSession) {
+	if session == nil {
+		c.SessionAuth, c.SessionCookie = "", ""
+		return
+	}
+	c.SessionAuth, c.SessionCookie = session.Auth, session.Cookie
+}
+
+// doUser performs a request against the userland /v1/ endpoints with the
+// login session, logging in first if needed. A request rejected as
+// unauthorized is sent once more after logging in again.
+func (c *Client) doUser(req *http.Request, v interface{}) error {
+	ctx := req.Context()
+	session, err := c.userSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	setSessionHeaders(req, session)
+	err = c.do(req, v)
+	if !errors.Is(err, ErrUnauthorized) || c.Password == "" || (req.Body != nil && req.GetBody == nil) {
+		return err
+	}
+
+	session, err = c.renewSession(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	retry := req.Clone(ctx)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return fmt.Errorf("error rewinding request body: %w", err)
+		}
+	}
+	setSessionHeaders(retry, session)
+	return c.do(retry, v)
+}
+
+func setSessionHeaders(req *http.Request, session *LoginSession) {
+	req.Header.Set("Authorization", session.Auth)
+	req.Header.Set("Cookie", "session="+session.Cookie)
+}
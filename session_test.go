@@ -0,0 +1,81 @@
+package ragflow_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+	"github.com/kevinroleke/ragflow-go/ragflowtest"
+)
+
+func TestReloginAfterSessionExpiry(t *testing.T) {
+	srv := ragflowtest.NewServer()
+	defer srv.Close()
+	client := srv.NewClient(ragflow.WithCredentials(ragflowtest.DefaultEmail, ragflowtest.DefaultPassword))
+	ctx := context.Background()
+
+	if _, _, err := client.Login(ctx); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	before, err := client.Session()
+	if err != nil || before == nil {
+		t.Fatalf("Session = %+v, %v", before, err)
+	}
+
+	srv.ExpireSessions()
+
+	if _, err := client.GetMyLLMs(ctx); err != nil {
+		t.Fatalf("GetMyLLMs after the session expired: %v", err)
+	}
+	after, err := client.Session()
+	if err != nil || after == nil || after.Auth == before.Auth {
+		t.Fatalf("Session after re-login = %+v, %v; want a new session", after, err)
+	}
+
+	srv.ExpireSessions()
+	ok, err := client.SetAPIKey(ctx, ragflow.SetAPIKeyRequest{FactoryName: "OpenAI", ApiKey: "sk-test"})
+	if err != nil || !ok {
+		t.Fatalf("SetAPIKey after the session expired = %v, %v", ok, err)
+	}
+}
+
+func TestFileSessionStore(t *testing.T) {
+	srv := ragflowtest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	client := srv.NewClient(
+		ragflow.WithCredentials(ragflowtest.DefaultEmail, ragflowtest.DefaultPassword),
+		ragflow.WithSessionStore(ragflow.NewFileSessionStore(path)),
+	)
+	if _, err := client.GetMyLLMs(ctx); err != nil {
+		t.Fatalf("GetMyLLMs: %v", err)
+	}
+	saved, err := ragflow.NewFileSessionStore(path).LoadSession()
+	if err != nil || saved == nil || saved.Auth == "" || saved.Email != ragflowtest.DefaultEmail {
+		t.Fatalf("LoadSession = %+v, %v", saved, err)
+	}
+
+	// A wrong password cannot log in, so the second client can only succeed
+	// with the stored session.
+	restored := srv.NewClient(
+		ragflow.WithCredentials(ragflowtest.DefaultEmail, "wrong"),
+		ragflow.WithSessionStore(ragflow.NewFileSessionStore(path)),
+	)
+	if _, err := restored.GetMyLLMs(ctx); err != nil {
+		t.Fatalf("GetMyLLMs with the stored session: %v", err)
+	}
+	if session, err := restored.Session(); err != nil || *session != *saved {
+		t.Fatalf("restored Session = %+v, %v; want %+v", session, err, saved)
+	}
+
+	if err := restored.Logout(ctx); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("session file after Logout: %v", err)
+	}
+}
@@ -0,0 +1,429 @@
+package ragflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type UploadOptions struct {
+	// ContentType of the uploaded file. Defaults to application/octet-stream.
+	ContentType string
+	// Size of the content in bytes, if known. When set the request is sent
+	// with a Content-Length instead of chunked encoding.
+	Size int64
+	// Progress is called as the file is sent with the number of bytes sent so
+	// far and the total size, or -1 if Size was not given.
+	Progress func(sent, total int64)
+}
+
+// UploadDocumentReader streams the content of r to the dataset as a single
+// document without buffering it in memory. If r is an io.Seeker the upload
+// can be retried under the client's retry policy.
+func (c *Client) UploadDocumentReader(ctx context.Context, datasetID, filename string, r io.Reader, opts *UploadOptions) (*Document, error) {
+	part := uploadPart{filename: filename, reader: r, size: -1}
+	var progress func(sent, total int64)
+	if opts != nil {
+		part.contentType = opts.ContentType
+		if opts.Size > 0 {
+			part.size = opts.Size
+		}
+		progress = opts.Progress
+	}
+
+	docs, err := c.postDocuments(ctx, datasetID, []uploadPart{part}, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no documents returned")
+	}
+
+	return &docs[0], nil
+}
+
+type uploadPart struct {
+	filename    string
+	contentType string
+	reader      io.Reader
+	// size is the content length in bytes, or -1 if unknown.
+	size int64
+}
+
+func (p uploadPart) header() textproto.MIMEHeader {
+	contentType := p.contentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, quoteEscaper.Replace(p.filename)))
+	h.Set("Content-Type", contentType)
+	return h
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// postDocuments streams parts to the documents endpoint as one multipart
+// request.
+func (c *Client) postDocuments(ctx context.Context, datasetID string, parts []uploadPart, progress func(sent, total int64)) ([]Document, error) {
+	endpoint := fmt.Sprintf("/api/v1/datasets/%s/documents", datasetID)
+
+	var result ArrayResponse[Document]
+	if err := c.postMultipart(ctx, endpoint, parts, progress, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Data, nil
+}
+
+// postMultipart streams parts to endpoint as one multipart request through
+// an io.Pipe and decodes the response into v.
+func (c *Client) postMultipart(ctx context.Context, endpoint string, parts []uploadPart, progress func(sent, total int64), v interface{}) error {
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+
+	total := int64(0)
+	seekable := true
+	for _, p := range parts {
+		if p.size < 0 {
+			total = -1
+		} else if total >= 0 {
+			total += p.size
+		}
+		if _, ok := p.reader.(io.Seeker); !ok {
+			seekable = false
+		}
+	}
+
+	var mu sync.Mutex
+	var writing chan struct{}
+	newBody := func() io.ReadCloser {
+		pr, pw := io.Pipe()
+		done := make(chan struct{})
+		mu.Lock()
+		writing = done
+		mu.Unlock()
+
+		go func() {
+			defer close(done)
+			pw.CloseWithError(writeMultipart(pw, boundary, parts, total, progress))
+		}()
+		return pr
+	}
+
+	// The body is attached once the request is built: its writer goroutine
+	// would block forever if building the request failed.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Body = newBody()
+
+	if overhead := multipartOverhead(boundary, parts); total >= 0 && overhead >= 0 {
+		req.ContentLength = overhead + total
+	}
+	if seekable {
+		req.GetBody = func() (io.ReadCloser, error) {
+			mu.Lock()
+			<-writing
+			mu.Unlock()
+			for _, p := range parts {
+				if _, err := p.reader.(io.Seeker).Seek(0, io.SeekStart); err != nil {
+					return nil, err
+				}
+			}
+			return newBody(), nil
+		}
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	c.logResponseBody(req, bodyBytes)
+
+	if err := c.checkAPIResponse(req, resp, bodyBytes); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(bodyBytes, v); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return nil
+}
+
+func writeMultipart(w io.Writer, boundary string, parts []uploadPart, total int64, progress func(sent, total int64)) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	var sent int64
+	for _, p := range parts {
+		part, err := mw.CreatePart(p.header())
+		if err != nil {
+			return fmt.Errorf("error creating form file: %w", err)
+		}
+
+		src := p.reader
+		if progress != nil {
+			src = &progressReader{r: src, sent: &sent, total: total, fn: progress}
+		}
+		if _, err := io.Copy(part, src); err != nil {
+			return fmt.Errorf("error copying file: %w", err)
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return fmt.Errorf("error closing writer: %w", err)
+	}
+	return nil
+}
+
+// multipartOverhead returns the number of bytes writeMultipart emits in
+// addition to the file contents.
+func multipartOverhead(boundary string, parts []uploadPart) int64 {
+	var cw countingWriter
+	mw := multipart.NewWriter(&cw)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return -1
+	}
+	for _, p := range parts {
+		if _, err := mw.CreatePart(p.header()); err != nil {
+			return -1
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return -1
+	}
+	return cw.n
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+type progressReader struct {
+	r     io.Reader
+	sent  *int64
+	total int64
+	fn    func(sent, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		*r.sent += int64(n)
+		r.fn(*r.sent, r.total)
+	}
+	return n, err
+}
+
+const (
+	defaultBatchBytes    = 32 << 20
+	defaultBatchFiles    = 16
+	defaultUploadWorkers = 4
+)
+
+// UploadFile describes one file of a batch upload. Either Path or Reader must
+// be set.
+type UploadFile struct {
+	// Path of a file on disk. It is opened only when its batch is sent.
+	Path string
+	// Reader supplies the content when Path is empty.
+	Reader io.Reader
+	// Name is the file name sent to the server. Defaults to the base name of Path.
+	Name string
+	// Size of Reader's content in bytes, if known. Files of unknown size are
+	// sent in a request of their own.
+	Size        int64
+	ContentType string
+}
+
+type UploadDocumentsOptions struct {
+	// MaxBatchBytes caps the combined size of the files sent in one request.
+	MaxBatchBytes int64
+	// MaxBatchFiles caps the number of files sent in one request.
+	MaxBatchFiles int
+	// Concurrency is the number of requests in flight at once.
+	Concurrency int
+}
+
+// UploadResult reports the outcome for one file of a batch upload. Exactly one
+// of Document and Err is set.
+type UploadResult struct {
+	File     UploadFile
+	Document *Document
+	Err      error
+}
+
+// UploadDocuments uploads files to the dataset, packing several files into each
+// multipart request and sending the requests concurrently. The returned results
+// are in the order of files. The error joins the errors of all failed files, so
+// a partial failure still returns the documents that were created.
+func (c *Client) UploadDocuments(ctx context.Context, datasetID string, files []UploadFile, opts *UploadDocumentsOptions) ([]UploadResult, error) {
+	maxBytes := int64(defaultBatchBytes)
+	maxFiles := defaultBatchFiles
+	workers := defaultUploadWorkers
+	if opts != nil {
+		if opts.MaxBatchBytes > 0 {
+			maxBytes = opts.MaxBatchBytes
+		}
+		if opts.MaxBatchFiles > 0 {
+			maxFiles = opts.MaxBatchFiles
+		}
+		if opts.Concurrency > 0 {
+			workers = opts.Concurrency
+		}
+	}
+
+	results := make([]UploadResult, len(files))
+	sizes := make([]int64, len(files))
+	for i, f := range files {
+		results[i].File = f
+		sizes[i] = -1
+
+		switch {
+		case f.Path != "":
+			info, err := os.Stat(f.Path)
+			if err != nil {
+				results[i].Err = fmt.Errorf("error reading file info: %w", err)
+				continue
+			}
+			sizes[i] = info.Size()
+		case f.Reader != nil:
+			if f.Size > 0 {
+				sizes[i] = f.Size
+			}
+		default:
+			results[i].Err = fmt.Errorf("upload file has neither Path nor Reader")
+		}
+	}
+
+	// Pack files greedily in order. Files of unknown size go alone.
+	var batches [][]int
+	var current []int
+	var currentBytes int64
+	for i := range files {
+		if results[i].Err != nil {
+			continue
+		}
+		size := sizes[i]
+		if len(current) > 0 && (size < 0 || len(current) >= maxFiles || currentBytes+size > maxBytes) {
+			batches = append(batches, current)
+			current, currentBytes = nil, 0
+		}
+		current = append(current, i)
+		if size < 0 {
+			batches = append(batches, current)
+			current, currentBytes = nil, 0
+			continue
+		}
+		currentBytes += size
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+
+	fail := func(batch []int, err error) {
+		for _, i := range batch {
+			results[i].Err = err
+		}
+	}
+
+	forEachConcurrent(ctx, len(batches), workers, func(b int) {
+		batch, docs, err := c.uploadBatch(ctx, datasetID, files, sizes, batches[b], results)
+		if len(batch) == 0 {
+			return
+		}
+		if err != nil {
+			fail(batch, err)
+			return
+		}
+		if len(docs) != len(batch) {
+			fail(batch, fmt.Errorf("server returned %d documents for %d files", len(docs), len(batch)))
+			return
+		}
+		for j, i := range batch {
+			doc := docs[j]
+			results[i].Document = &doc
+		}
+	}, func(b int, err error) {
+		fail(batches[b], err)
+	})
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", uploadFileName(r.File), r.Err))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// uploadBatch sends the files of batch that can be opened in one request and
+// returns their indices. Files that cannot be opened get the error in their
+// result and are left out.
+func (c *Client) uploadBatch(ctx context.Context, datasetID string, files []UploadFile, sizes []int64, batch []int, results []UploadResult) ([]int, []Document, error) {
+	sent := make([]int, 0, len(batch))
+	parts := make([]uploadPart, 0, len(batch))
+	for _, i := range batch {
+		f := files[i]
+		reader := f.Reader
+		if f.Path != "" {
+			file, err := os.Open(f.Path)
+			if err != nil {
+				results[i].Err = fmt.Errorf("error opening file: %w", err)
+				continue
+			}
+			defer file.Close()
+			reader = file
+		}
+
+		sent = append(sent, i)
+		parts = append(parts, uploadPart{
+			filename:    uploadFileName(f),
+			contentType: f.ContentType,
+			reader:      reader,
+			size:        sizes[i],
+		})
+	}
+	if len(parts) == 0 {
+		return nil, nil, nil
+	}
+
+	docs, err := c.postDocuments(ctx, datasetID, parts, nil)
+	return sent, docs, err
+}
+
+func uploadFileName(f UploadFile) string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return filepath.Base(f.Path)
+}
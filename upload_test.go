@@ -0,0 +1,83 @@
+package ragflow_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	ragflow "github.com/kevinroleke/ragflow-go"
+	"github.com/kevinroleke/ragflow-go/ragflowtest"
+)
+
+func TestUploadWithBadBaseURLDoesNotLeak(t *testing.T) {
+	client := ragflow.NewClient("key", ragflow.WithBaseURL("http://bad host"))
+	ctx := context.Background()
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		if _, err := client.UploadDocumentReader(ctx, "ds", "a.txt", strings.NewReader("content"), nil); err == nil {
+			t.Fatal("UploadDocumentReader with a malformed base URL succeeded")
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("%d goroutines left running after failed uploads", n-before)
+	}
+}
+
+func TestUploadDocumentsKeepsBatchWhenFileCannotBeOpened(t *testing.T) {
+	srv := ragflowtest.NewServer()
+	defer srv.Close()
+	client := srv.NewClient()
+	ctx := context.Background()
+
+	ds, err := client.CreateDataset(ctx, ragflow.CreateDatasetRequest{Name: "docs"})
+	if err != nil {
+		t.Fatalf("CreateDataset: %v", err)
+	}
+
+	// A socket passes the size check but cannot be opened.
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "c.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("cannot create a unix socket: %v", err)
+	}
+	defer l.Close()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := client.UploadDocuments(ctx, ds.ID, []ragflow.UploadFile{
+		{Path: filepath.Join(dir, "a.txt")},
+		{Path: socket},
+		{Path: filepath.Join(dir, "b.txt")},
+	}, nil)
+	if err == nil {
+		t.Fatal("UploadDocuments reported no error for the socket")
+	}
+	if results[1].Err == nil || results[1].Document != nil {
+		t.Errorf("socket result = %+v, want an error", results[1])
+	}
+	for _, i := range []int{0, 2} {
+		if results[i].Err != nil || results[i].Document == nil {
+			t.Errorf("result %d = %+v, want a document", i, results[i])
+		}
+	}
+
+	docs, err := client.ListDocuments(ctx, ds.ID, nil)
+	if err != nil || docs.Data.Total != 2 {
+		t.Fatalf("ListDocuments = %+v, %v", docs, err)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"io"
 	"net/http"
 )
@@ -69,13 +68,6 @@ func (c *Client) newUserRequest(ctx context.Context, method, endpoint string, bo
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
-	if c.SessionAuth != "" {
-		req.Header.Set("Authorization", c.SessionAuth)
-	}
-	if c.SessionCookie != "" {
-		req.Header.Set("Cookie", "session="+c.SessionCookie)
-	}
-
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
@@ -89,24 +81,12 @@ func (c *Client) GetMyLLMs(ctx context.Context) (MyLLMsResponse, error) {
 		return nil, err
 	}
 
-	httpRes, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer httpRes.Body.Close()
-	bytes, err := io.ReadAll(httpRes.Body)
-	if err != nil {
-		return nil, err
-	}
-	log.Println("RAGFLOW USERLAND RESP:", string(bytes))
-
 	var response Response[MyLLMsResponse]
-	err = json.Unmarshal(bytes, &response)
-	if err != nil {
+	if err := c.doUser(req, &response); err != nil {
 		return nil, err
 	}
 
-	return response.Data, err
+	return response.Data, nil
 }
 
 func (c *Client) GetFactories(ctx context.Context) ([]Factory, error) {
@@ -115,23 +95,12 @@ func (c *Client) GetFactories(ctx context.Context) ([]Factory, error) {
 		return nil, err
 	}
 
-	httpRes, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer httpRes.Body.Close()
-	bytes, err := io.ReadAll(httpRes.Body)
-	if err != nil {
-		return nil, err
-	}
-
 	var response Response[[]Factory]
-	err = json.Unmarshal(bytes, &response)
-	if err != nil {
+	if err := c.doUser(req, &response); err != nil {
 		return nil, err
 	}
 
-	return response.Data, err
+	return response.Data, nil
 }
 
 func (c *Client) SetAPIKey(ctx context.Context, params SetAPIKeyRequest) (bool, error) {
@@ -140,27 +109,16 @@ func (c *Client) SetAPIKey(ctx context.Context, params SetAPIKeyRequest) (bool,
 		return false, err
 	}
 
-	httpRes, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return false, err
-	}
-	defer httpRes.Body.Close()
-	bytes, err := io.ReadAll(httpRes.Body)
-	if err != nil {
-		return false, err
-	}
-
 	var response Response[bool]
-	err = json.Unmarshal(bytes, &response)
-	if err != nil {
+	if err := c.doUser(req, &response); err != nil {
 		return false, err
 	}
 
 	if !response.Data {
-		return false, fmt.Errorf(response.Message)
+		return false, newAPIError(req, nil, response.Code, response.Message)
 	}
 
-	return response.Data, err
+	return response.Data, nil
 }
 
 func (c *Client) AddLLM(ctx context.Context, params AddLLMRequest) (bool, error) {
@@ -169,25 +127,14 @@ func (c *Client) AddLLM(ctx context.Context, params AddLLMRequest) (bool, error)
 		return false, err
 	}
 
-	httpRes, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return false, err
-	}
-	defer httpRes.Body.Close()
-	bytes, err := io.ReadAll(httpRes.Body)
-	if err != nil {
-		return false, err
-	}
-
 	var response Response[bool]
-	err = json.Unmarshal(bytes, &response)
-	if err != nil {
+	if err := c.doUser(req, &response); err != nil {
 		return false, err
 	}
 
 	if !response.Data {
-		return false, fmt.Errorf(response.Message)
+		return false, newAPIError(req, nil, response.Code, response.Message)
 	}
 
-	return response.Data, err
+	return response.Data, nil
 }